@@ -0,0 +1,755 @@
+package backoff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SteadyStateRate returns the average number of retries per second that a
+// single client generates once the backoff has stopped growing, i.e. the
+// reciprocal of the plateau delay (accounting for any constant fallback).
+// Multiplied by the number of clients, this gives the floor of the load that
+// a downstream must absorb during a sustained outage. In full (or equal)
+// jitter mode, delays average 1/2 (or 3/4) of the plateau delay, so the rate
+// is higher. If the plateau delay is 0, the rate is unbounded and +Inf is
+// returned. If growth is unlimited, i.e. the delay only stops growing once it
+// saturates, there is no steady state and 0 is returned.
+func (b *Backoff) SteadyStateRate() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.steadyStateRate()
+}
+
+func (b *Backoff) steadyStateRate() float64 {
+	plateau := b.plateauDelay()
+	if plateau == math.MaxInt64 {
+		return 0
+	}
+	d := float64(plateau)
+	if b.fallbackProb > 0 {
+		d = (1-b.fallbackProb)*d + b.fallbackProb*float64(b.fallbackDelay)
+	}
+	if !b.limitAsFloor {
+		switch {
+		case b.jitterMode == JitterFull:
+			d /= 2
+		case b.jitterMode == JitterEqual:
+			d *= 0.75
+		case b.jitterBounds:
+			// the mean of asymmetric bounds is not the nominal delay
+			d *= 1 + (b.jitterLow+b.jitterHigh)/2
+		}
+	}
+	if d <= 0 {
+		return math.Inf(1)
+	}
+	return float64(time.Second) / d
+}
+
+// plateauDelay returns the pre-jitter delay at which the backoff stops growing.
+// Rather than step through every round, which takes O(limit/base) rounds with
+// linear growth, it leaps straight to the limit where the growth allows it.
+func (b *Backoff) plateauDelay() time.Duration {
+	p := b.progress
+	// the max delay caps growth too, even below the exponential limit
+	for !b.plateaued(p) && (b.maxDelay == 0 || p.delay < b.maxDelay) {
+		p = b.leap(p)
+	}
+	return p.delay
+}
+
+// exactRounds is the number of rounds of exponential growth below which leap
+// steps one round at a time, so that the plateau matches the rounded delays of
+// the actual sequence.
+const exactRounds = 64
+
+// leap returns the progress of the backoff after the rounds it takes to reach
+// the limit (or the max doublings) from the round described by p, or after that
+// round alone if the growth must be stepped, e.g. Fibonacci growth, which
+// reaches any limit within O(log) rounds anyway.
+func (b *Backoff) leap(p progress) progress {
+	if len(b.replay) > 0 || p.delay == 0 || b.baseAlways && p.rounds == 0 {
+		return b.grow(p)
+	}
+	if b.softLimit && b.pastLimit(p) {
+		// creep linearly up to the max delay
+		p.rounds += ceilDiv(b.maxDelay-p.delay, b.baseDelay)
+		p.delay = b.maxDelay
+		return p
+	}
+
+	var n int // the rounds to reach the limit
+	switch b.growth {
+	case GrowthLinear:
+		n = ceilDiv(b.expLimit-p.delay, b.baseDelay)
+	case GrowthExponential:
+		n = int(math.Ceil(math.Log(float64(b.expLimit)/float64(p.delay)) / math.Log(b.multiplier)))
+		if n <= exactRounds {
+			return b.grow(p)
+		}
+	default:
+		return b.grow(p)
+	}
+	if b.maxDoublings > 0 {
+		n = min(n, b.maxDoublings-p.grown)
+	}
+	n = max(n, 1)
+
+	if b.growth == GrowthLinear {
+		if time.Duration(n) > (math.MaxInt64-p.delay)/b.baseDelay {
+			p.delay = math.MaxInt64
+		} else {
+			p.delay += time.Duration(n) * b.baseDelay
+		}
+	} else if next := float64(p.delay) * math.Pow(b.multiplier, float64(n)); next >= math.MaxInt64 {
+		p.delay = math.MaxInt64
+	} else {
+		p.delay = time.Duration(next)
+	}
+	if b.maxDelay > 0 {
+		p.delay = min(p.delay, b.maxDelay)
+	}
+	p.grown += n
+	p.rounds += n
+	return p
+}
+
+// ceilDiv returns the number of steps of size step it takes to cover d.
+func ceilDiv(d, step time.Duration) int {
+	n := d / step
+	if d%step != 0 {
+		n++
+	}
+	return int(n)
+}
+
+// ExampleSchedule returns human-readable descriptions of the first n rounds of
+// a fresh copy of the backoff, e.g. "wait 425~575ms ~500ms", in the format used
+// by the package documentation. It is built from the actual configuration, so
+// it can be used to generate documentation that never drifts from behavior.
+// The backoff itself is not advanced.
+func (b *Backoff) ExampleSchedule(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.clone()
+	lines := make([]string, 0, max(n, 0))
+	for i := 0; i < n; i++ {
+		lo, hi := c.PeekRange()
+		switch {
+		case c.delay == 0:
+			lines = append(lines, "immediately retry")
+		case lo == hi:
+			lines = append(lines, fmt.Sprintf("wait %s", formatDelay(c.delay)))
+		default:
+			lines = append(lines, fmt.Sprintf("wait %s~%sms ~%s", formatMillis(lo), formatMillis(hi), formatDelay(c.delay)))
+		}
+		c.progress = c.grow(c.progress)
+	}
+	return lines
+}
+
+// formatDelay formats d like the package documentation does: as a duration
+// below 1s, and as a number of seconds from then on, e.g. "64s" rather than
+// "1m4s".
+func formatDelay(d time.Duration) string {
+	if d < time.Second {
+		return d.String()
+	}
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// formatMillis formats d as a number of milliseconds, without a unit.
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
+
+// TuneForDeadline adjusts the exponential limit, and if need be the
+// multiplier, so that the next attempts delays (before jitter) add up to
+// roughly the remaining time, adapting a generic backoff to a known deadline at
+// call time. Since the delay plateaus at the first step of the sequence that
+// reaches the limit, the limit is chosen from those steps to make the total as
+// close as possible to remaining. If even the uncapped sequence falls short of
+// remaining, the multiplier of exponential growth is raised until it does not.
+// The limit is kept within any min and max delays. It returns an error, and
+// leaves the backoff unchanged, if remaining or attempts is not positive.
+func (b *Backoff) TuneForDeadline(remaining time.Duration, attempts int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if remaining <= 0 {
+		return errors.New("the remaining time must be > 0")
+	}
+	if attempts <= 0 {
+		return errors.New("the attempts must be > 0")
+	}
+
+	c := b.clone()
+	c.progress = b.progress
+	c.expLimit = math.MaxInt64
+	if c.growth == GrowthExponential && c.totalDelay(attempts) < remaining {
+		c.multiplier = c.tuneMultiplier(remaining, attempts)
+	}
+
+	// the candidate limits are the steps of the uncapped sequence
+	candidates := make([]time.Duration, 0, attempts)
+	for p, i := c.progress, 0; i < attempts; p, i = c.grow(p), i+1 {
+		candidates = append(candidates, p.delay)
+		if p.delay > remaining {
+			break
+		}
+	}
+
+	bestLimit, bestDiff := b.expLimit, time.Duration(math.MaxInt64)
+	for _, limit := range candidates {
+		c.expLimit = limit
+		diff := c.totalDelay(attempts) - remaining
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff < bestDiff {
+			bestLimit, bestDiff = limit, diff
+		}
+	}
+	if b.maxDelay > 0 {
+		bestLimit = min(bestLimit, b.maxDelay)
+	}
+	b.expLimit = max(bestLimit, b.minDelay)
+	b.multiplier = c.multiplier
+	return nil
+}
+
+// maxTuneMultiplier is the largest multiplier TuneForDeadline uses to stretch
+// the schedule.
+const maxTuneMultiplier = 1 << 10
+
+// tuneMultiplier returns the smallest multiplier (to within a bisection) at
+// which the first attempts delays of the backoff, which must not be capped by
+// the exponential limit, add up to at least remaining. If no multiplier up to
+// maxTuneMultiplier does, e.g. because the max delay caps the sequence, the
+// multiplier is left unchanged.
+func (b *Backoff) tuneMultiplier(remaining time.Duration, attempts int) float64 {
+	c := b.clone()
+	c.progress = b.progress
+	c.expLimit = math.MaxInt64
+	total := func(m float64) time.Duration {
+		c.multiplier = m
+		return c.totalDelay(attempts)
+	}
+
+	lo, hi := b.multiplier, b.multiplier
+	for total(hi) < remaining {
+		if hi >= maxTuneMultiplier {
+			return b.multiplier
+		}
+		lo, hi = hi, min(hi*2, maxTuneMultiplier)
+	}
+	for i := 0; i < 32; i++ {
+		mid := (lo + hi) / 2
+		if total(mid) < remaining {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
+
+// ExpectedTotal returns the total wait of the first n delays of a fresh copy
+// of the backoff, i.e. the sum of the delays before jitter, which is also the
+// expected total with symmetric jitter. It accounts for the exponential limit,
+// so it can be used to choose timeouts and max attempts: with an exponential
+// limit of 0 the delays never grow, and with an initial delay of 0 the first
+// delay adds nothing. The total saturates rather than overflowing. If n is not
+// positive, 0 is returned.
+func (b *Backoff) ExpectedTotal(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.clone().totalDelay(n)
+}
+
+// totalDelay returns the sum of the next n delays, before jitter, saturating
+// rather than overflowing.
+func (b *Backoff) totalDelay(n int) time.Duration {
+	var total time.Duration
+	for p, i := b.progress, 0; i < n; p, i = b.grow(p), i+1 {
+		if b.plateaued(p) {
+			// the remaining delays are all the same
+			rest := int64(n - i)
+			if p.delay > 0 && rest > (math.MaxInt64-int64(total))/int64(p.delay) {
+				return math.MaxInt64
+			}
+			return total + time.Duration(rest)*p.delay
+		}
+		total = saturatingAdd(total, p.delay)
+	}
+	return total
+}
+
+// MaxClients estimates how many clients using this backoff can share a
+// downstream that absorbs at most downstreamRate retries per second, during a
+// sustained outage. The rate is not a number of concurrent retries: for a
+// downstream limited to c concurrent retries that each take d seconds, the
+// rate is c/d, by Little's law.
+//
+// The model assumes each client has reached the plateau, and that jitter has
+// decorrelated the clients, so their combined retries arrive as a Poisson
+// process. Each client is assumed to retry at the fastest rate the jitter band
+// allows (the plateau delay shortened by the lower edge of the jitter). The
+// estimate is conservative: the client count is chosen so that the expected
+// arrival rate plus three standard deviations stays within downstreamRate. In
+// full jitter mode, with absolute jitter at least as large as the plateau
+// delay, or with jitter bounds from a lowFrac of -1, the lower edge of the
+// jitter is 0, so the fastest rate is unbounded, and 0 is returned. With
+// unlimited growth, there is no steady state, and math.MaxInt is returned.
+func (b *Backoff) MaxClients(downstreamRate float64) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// the ratio of the lower edge of the jitter band to the mean delay
+	shortening := 1 - b.jitterFactor/2
+	if b.absJitter > 0 {
+		shortening = 1 - float64(b.absJitter)/float64(b.plateauDelay())
+	}
+	if b.maxJitter > 0 {
+		shortening = max(shortening, 1-float64(b.maxJitter)/float64(b.plateauDelay()))
+	}
+	if !b.limitAsFloor {
+		switch {
+		case b.jitterMode == JitterFull:
+			return 0
+		case b.jitterMode == JitterEqual:
+			shortening = 2.0 / 3
+		case b.jitterBounds:
+			shortening = (1 + b.jitterLow) / (1 + (b.jitterLow+b.jitterHigh)/2)
+		}
+	}
+	rate := b.steadyStateRate() / shortening
+	if downstreamRate <= 0 || !(shortening > 0) || math.IsInf(rate, 1) {
+		return 0
+	}
+	if rate == 0 {
+		// unlimited growth eventually makes room for any number of clients
+		return math.MaxInt
+	}
+
+	// largest mean m such that m + 3*sqrt(m) <= the downstream rate
+	sqrtMean := (math.Sqrt(9+4*downstreamRate) - 3) / 2
+	return int(math.Floor(sqrtMean * sqrtMean / rate))
+}
+
+// fingerprintRounds is the number of rounds of the schedule in a Fingerprint.
+const fingerprintRounds = 32
+
+// Fingerprint returns a stable hash of the backoff's observable behavior: the
+// first rounds of its schedule (before jitter), starting from its initial
+// state, plus the settings that apply on top of it, such as its jitter
+// configuration and its min and max delays. Backoffs that behave identically
+// share a fingerprint, even if configured differently, so a single golden
+// string can be used to assert that a schedule did not change across versions
+// or config edits. It does not depend on the current progress of the backoff.
+func (b *Backoff) Fingerprint() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var sb strings.Builder
+	sb.WriteString("schedule=")
+	for i, d := range b.schedule(fingerprintRounds) {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatInt(int64(d), 10))
+	}
+	fmt.Fprintf(&sb, ";jitter=%g;floor=%t", b.jitterFactor, b.limitAsFloor)
+	if b.jitterMode != JitterSymmetric {
+		fmt.Fprintf(&sb, ";mode=%v", b.jitterMode)
+	}
+	if b.absJitter > 0 {
+		fmt.Fprintf(&sb, ";absJitter=%d", b.absJitter)
+	}
+	if b.jitterBounds {
+		fmt.Fprintf(&sb, ";bounds=%g,%g", b.jitterLow, b.jitterHigh)
+	}
+	if b.maxJitter > 0 {
+		fmt.Fprintf(&sb, ";maxJitter=%d", b.maxJitter)
+	}
+	if b.fallbackProb > 0 {
+		fmt.Fprintf(&sb, ";fallback=%g,%d", b.fallbackProb, b.fallbackDelay)
+	}
+	if b.pauseAfter > 0 {
+		fmt.Fprintf(&sb, ";pause=%d,%d", b.pauseAfter, b.pauseDelay)
+	}
+	if b.slot > 0 {
+		fmt.Fprintf(&sb, ";slots=%d,%d", b.slot, b.slotOffset)
+	}
+	if b.startupSpread > 0 {
+		fmt.Fprintf(&sb, ";spread=%d", b.startupSpread)
+	}
+	// the min and max delays also clamp the delays after jitter, which the
+	// schedule does not show
+	if b.minDelay > 0 {
+		fmt.Fprintf(&sb, ";min=%d", b.minDelay)
+	}
+	if b.maxDelay > 0 {
+		fmt.Fprintf(&sb, ";max=%d", b.maxDelay)
+	}
+
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Schedule returns the first n delays (before jitter) of a fresh copy of the
+// backoff, including any scripted by WithSchedule, e.g. to preview the retry
+// schedule in documentation or dashboards, or to test it deterministically.
+// The backoff itself is not advanced.
+func (b *Backoff) Schedule(n int) []time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.schedule(n)
+}
+
+// schedule returns the first n delays (before jitter) of a fresh copy of the
+// backoff, including any scripted delays.
+func (b *Backoff) schedule(n int) []time.Duration {
+	c := b.clone()
+	delays := make([]time.Duration, 0, max(n, 0))
+	for i := 0; i < n; i++ {
+		if i < len(c.script) {
+			delays = append(delays, c.script[i])
+			continue
+		}
+		delays = append(delays, c.delay)
+		c.progress = c.grow(c.progress)
+	}
+	return delays
+}
+
+// CatchUp returns offsets, relative to now, at which to issue missedAttempts
+// attempts that were missed (e.g. while a scheduler was down), compressed to
+// fit within the given window. The offsets preserve the relative spacing of
+// the next delays of the backoff (before jitter), scaled so that the final
+// attempt lands at the end of the window, which recovers the backlog with
+// increasing spacing rather than as a single burst. The backoff is not
+// advanced. If missedAttempts is not positive, or within is negative, nil is
+// returned.
+func (b *Backoff) CatchUp(missedAttempts int, within time.Duration) []time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if missedAttempts <= 0 || within < 0 {
+		return nil
+	}
+
+	cumulative := make([]float64, missedAttempts)
+	var total float64
+	for p, i := b.progress, 0; i < missedAttempts; p, i = b.grow(p), i+1 {
+		total += float64(p.delay)
+		cumulative[i] = total
+	}
+
+	offsets := make([]time.Duration, missedAttempts)
+	if total == 0 {
+		return offsets
+	}
+	scale := float64(within) / total
+	for i, c := range cumulative {
+		offsets[i] = time.Duration(math.Round(c * scale))
+	}
+	return offsets
+}
+
+// entropyBucket is the resolution at which delays are assumed to be
+// observable, for TimingEntropy.
+const entropyBucket = time.Millisecond
+
+// TimingEntropy estimates the Shannon entropy, in bits, of the jittered delay
+// at the given attempt (counting from 0 for the first delay of a fresh
+// backoff), i.e. how hard the delay is to predict, and so to use as a
+// fingerprint. Delays are quantized into 1ms buckets, on the assumption that
+// an observer cannot resolve timing more finely than that, so the entropy is
+// 0 when jitter moves the delay by less than a millisecond. Only the jitter
+// about the exponential delay is considered, not any constant fallback.
+func (b *Backoff) TimingEntropy(attempt int) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if attempt < 0 {
+		return 0
+	}
+	p := b.progressAt(attempt)
+	lo, hi := b.jitterRange(p.delay, b.limitAsFloor && b.plateaued(p))
+	return uniformEntropy(lo, hi, float64(entropyBucket))
+}
+
+// uniformEntropy returns the entropy, in bits, of a uniform distribution over
+// [lo, hi] once quantized into buckets of the given size.
+func uniformEntropy(lo, hi, bucket float64) float64 {
+	width := hi - lo
+	if width <= 0 {
+		return 0
+	}
+
+	var h float64
+	add := func(mass float64) {
+		if p := mass / width; p > 0 {
+			h -= p * math.Log2(p)
+		}
+	}
+	first, last := math.Floor(lo/bucket), math.Floor(hi/bucket)
+	if first == last {
+		return 0
+	}
+	add((first+1)*bucket - lo)
+	add(hi - last*bucket)
+	if full := last - first - 1; full > 0 {
+		p := bucket / width
+		h -= full * p * math.Log2(p)
+	}
+	return h
+}
+
+// EffectiveJitterResolution returns the width of the jitter band at the base
+// delay. If it is below the resolution at which delays are logged or measured
+// (e.g. 1ms), jitter is effectively invisible at this delay scale, which
+// usually means the jitter factor is too small for the base delay. It depends
+// only on the configuration.
+func (b *Backoff) EffectiveJitterResolution() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lo, hi := b.jitterRange(b.baseDelay, false)
+	return time.Duration(math.Round(hi - lo))
+}
+
+// AttemptsWithinBudget returns the range of the number of delays, starting
+// from the current state of the backoff, that can fit within the budget,
+// accounting for jitter. The minimum assumes every delay falls at the top of
+// its jitter band, and the maximum assumes every delay falls at the bottom. Any
+// max attempts limit caps both, while any constant fallback is not considered.
+// Like the delays that Next returns, the delays are clamped by any min and max
+// delays. If delays of 0 repeat forever, and there is no max attempts limit,
+// the maximum is math.MaxInt.
+func (b *Backoff) AttemptsWithinBudget(budget time.Duration) (minAttempts, maxAttempts int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	minAttempts = b.attemptsWithin(budget, func(lo, hi float64) float64 { return hi })
+	maxAttempts = b.attemptsWithin(budget, func(lo, hi float64) float64 { return lo })
+	return minAttempts, maxAttempts
+}
+
+// attemptsWithin returns the number of delays that fit within the budget, with
+// each delay picked from its jitter band.
+func (b *Backoff) attemptsWithin(budget time.Duration, pick func(lo, hi float64) float64) int {
+	limit := math.MaxInt
+	if b.maxAttempts > 0 {
+		limit = max(b.maxAttempts-b.attempts, 0)
+	}
+
+	var total float64
+	n, p := 0, b.progress
+	for n < limit {
+		d := pick(b.clampRange(b.jitterRange(p.delay, b.limitAsFloor && b.plateaued(p))))
+		if b.plateaued(p) || b.maxDelay > 0 && p.delay >= b.maxDelay {
+			// every further delay is the same
+			if d <= 0 {
+				return limit
+			}
+			k := math.Floor((float64(budget) - total) / d)
+			return n + int(math.Min(k, float64(limit-n)))
+		}
+		if total+d > float64(budget) {
+			break
+		}
+		total += d
+		n++
+		p = b.grow(p)
+	}
+	return n
+}
+
+// sparkBlocks are the bars of a sparkline, from lowest to highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders the first n delays (before jitter) of a fresh copy of the
+// backoff as a single line of n bars, scaled to the largest delay, e.g.
+// "▁▁▁▁▂▄█████" for an exponential curve flattening at the limit. If every
+// delay is 0, every bar is at the lowest level.
+func (b *Backoff) Sparkline(n int) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delays := b.schedule(n)
+	var peak time.Duration
+	for _, d := range delays {
+		peak = max(peak, d)
+	}
+
+	bars := make([]rune, len(delays))
+	top := len(sparkBlocks) - 1
+	for i, d := range delays {
+		level := 0
+		if peak > 0 {
+			level = int(math.Ceil(float64(d)/float64(peak)*float64(len(sparkBlocks)))) - 1
+		}
+		bars[i] = sparkBlocks[min(max(level, 0), top)]
+	}
+	return string(bars)
+}
+
+// DelayAt returns the delay (before jitter) at the given attempt, counting from
+// 0 for the first delay of a fresh backoff. A negative attempt returns 0.
+func (b *Backoff) DelayAt(attempt int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if attempt < 0 {
+		return 0
+	}
+	return b.progressAt(attempt).delay
+}
+
+// WorstCaseDelay returns the longest delay possible at the given attempt,
+// counting from 0 for the first delay of a fresh backoff, i.e. DelayAt plus
+// the upper edge of the jitter band (also considering any constant fallback),
+// or, with decorrelated jitter, the most that the previous delays allow, plus
+// any startup spread for the first delay, within any min and max delays. This
+// is the value to set timeouts and alerts against. It does not account for
+// plateau pauses or delay overrides. A negative attempt returns 0.
+func (b *Backoff) WorstCaseDelay(attempt int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if attempt < 0 {
+		return 0
+	}
+	var hi float64
+	if b.slot > 0 {
+		hi = float64(b.slot + b.slotOffset)
+	} else {
+		p := b.progressAt(attempt)
+		_, hi = b.jitterRange(p.delay, b.limitAsFloor && b.plateaued(p))
+		if b.jitterMode == JitterDecorrelated {
+			// the previous delay is at most the base delay grown threefold per round
+			limit := float64(b.expLimit)
+			hi = float64(b.baseDelay) * 3
+			for i := 0; i < attempt && hi < limit; i++ {
+				hi *= 3
+			}
+			hi = math.Min(hi, limit)
+		}
+		if b.fallbackProb > 0 {
+			_, fhi := b.jitterRange(b.fallbackDelay, false)
+			hi = math.Max(hi, fhi)
+		}
+	}
+	if attempt == 0 {
+		hi += float64(b.startupSpread)
+	}
+	hi = math.Max(hi, float64(b.minDelay))
+	if b.maxDelay > 0 {
+		hi = math.Min(hi, float64(b.maxDelay))
+	}
+	return time.Duration(math.Round(hi))
+}
+
+// progressAt returns the progress of a fresh backoff after attempt rounds.
+func (b *Backoff) progressAt(attempt int) progress {
+	c := b.clone()
+	p := c.progress
+	for i := 0; i < attempt && !c.plateaued(p); i++ {
+		p = c.grow(p)
+	}
+	return p
+}
+
+// AllocateBudget splits a total retry budget across phases of an operation
+// (e.g. connect, authenticate, fetch) in proportion to the weights, and
+// returns one backoff per phase, in its initial state, configured like b but
+// with a max elapsed time equal to the phase's share of the total. Running a
+// retry loop per phase with these backoffs collectively respects the total
+// budget. The shares add up to exactly the total. If there are no weights, or
+// any weight is not positive, or the total is not positive, nil is returned.
+func (b *Backoff) AllocateBudget(total time.Duration, weights []float64) []*Backoff {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if total <= 0 || len(weights) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, w := range weights {
+		if !(w > 0) || math.IsInf(w, 1) {
+			return nil
+		}
+		sum += w
+	}
+
+	phases := make([]*Backoff, len(weights))
+	var cumulative float64
+	var allocated time.Duration
+	for i, w := range weights {
+		cumulative += w
+		end := time.Duration(math.Round(float64(total) * cumulative / sum))
+		if i == len(weights)-1 {
+			end = total
+		}
+		phases[i] = b.clone()
+		phases[i].maxElapsed = end - allocated
+		allocated = end
+	}
+	return phases
+}
+
+// ExpectedRetriesUntilSuccess returns the expected number of retries (i.e.
+// attempts after the first) made before an operation succeeds, given the
+// probability that any single attempt fails, independently of the others. The
+// number of attempts is geometrically distributed, so without a max attempts
+// limit this is failureProb/(1-failureProb), and with one, the count is
+// truncated at the limit. If failureProb is not in [0, 1), NaN is returned.
+func (b *Backoff) ExpectedRetriesUntilSuccess(failureProb float64) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !(failureProb >= 0 && failureProb < 1) {
+		return math.NaN()
+	}
+	q := failureProb
+	if b.maxAttempts > 0 {
+		return q * (1 - math.Pow(q, float64(b.maxAttempts))) / (1 - q)
+	}
+	return q / (1 - q)
+}
+
+// ExpectedTimeUntilSuccess returns the expected total delay of a fresh backoff
+// before an operation succeeds, given the probability that any single attempt
+// fails, independently of the others. Each delay is weighted by the
+// probability of reaching it, i.e. of every attempt before it failing, and
+// contributes its mean (accounting for jitter and any constant fallback). The
+// time spent in the operation itself, plateau pauses, delay overrides and
+// global slots are not considered. If failureProb is not in [0, 1), 0 is
+// returned.
+func (b *Backoff) ExpectedTimeUntilSuccess(failureProb float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !(failureProb >= 0 && failureProb < 1) {
+		return 0
+	}
+	q := failureProb
+
+	c := b.clone()
+	var total float64
+	reach := q
+	for p, k := c.progress, 0; reach > 0 && (c.maxAttempts == 0 || k < c.maxAttempts); p, k = c.grow(p), k+1 {
+		lo, hi := c.jitterRange(p.delay, c.limitAsFloor && c.plateaued(p))
+		mean := (lo + hi) / 2
+		if c.fallbackProb > 0 {
+			mean = (1-c.fallbackProb)*mean + c.fallbackProb*float64(c.fallbackDelay)
+		}
+		if c.plateaued(p) {
+			// the remaining delays are all the same, so sum them in closed form
+			remaining := math.Inf(1)
+			if c.maxAttempts > 0 {
+				remaining = float64(c.maxAttempts - k)
+			}
+			total += mean * reach * (1 - math.Pow(q, remaining)) / (1 - q)
+			break
+		}
+		total += mean * reach
+		reach *= q
+	}
+	return time.Duration(math.Round(total))
+}
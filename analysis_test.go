@@ -0,0 +1,596 @@
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSteadyStateRate(t *testing.T) {
+	tests := map[string]struct {
+		b    *Backoff
+		want float64
+	}{
+		"plateau at the exp limit": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*4)),
+			0.25,
+		},
+		"plateau overshoots the exp limit": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*3)),
+			0.25,
+		},
+		"plateau at the base delay with 0 exp limit": {
+			CoerceNew(WithInitialDelay(0), WithBaseDelay(time.Millisecond*500), WithExponentialLimit(0)),
+			2,
+		},
+		"constant fallback lowers the mean delay": {
+			CoerceNew(
+				WithInitialDelay(time.Second*4),
+				WithExponentialLimit(time.Second*4),
+				WithConstantFallback(0.5, 0),
+			),
+			0.5,
+		},
+		"zero plateau is unbounded": {
+			CoerceNew(WithConstantFallback(1, 0)),
+			math.Inf(1),
+		},
+		"jitter bounds shift the mean delay": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithJitterBounds(0, 2)),
+			0.5,
+		},
+		"full jitter halves the mean delay": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*4), WithJitterMode(JitterFull)),
+			0.5,
+		},
+		"equal jitter shortens the mean delay": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*3), WithJitterMode(JitterEqual)),
+			1.0 / 3,
+		},
+		"linear growth with a tiny base delay": {
+			CoerceNew(WithGrowth(GrowthLinear), WithBaseDelay(1), WithExponentialLimit(time.Hour*24*365)),
+			1.0 / (24 * 365 * 3600),
+		},
+		"plateau at a max delay below the exp limit": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Hour), WithMaxDelay(time.Second*4)),
+			0.25,
+		},
+		"unlimited growth has no steady state": {
+			CoerceNew(WithExponentialLimit(math.MaxInt64)),
+			0,
+		},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.b.SteadyStateRate(); got != tc.want {
+				t.Fatalf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExampleSchedule(t *testing.T) {
+	// the configuration used in the package documentation
+	b := CoerceNew(
+		WithInitialDelay(0),
+		WithBaseDelay(time.Millisecond*500),
+		WithExponentialLimit(time.Second*60),
+	)
+	b.computeDelay()
+	want := []string{
+		"immediately retry",
+		"wait 425~575ms ~500ms",
+		"wait 850~1150ms ~1s",
+		"wait 1700~2300ms ~2s",
+		"wait 3400~4600ms ~4s",
+		"wait 6800~9200ms ~8s",
+		"wait 13600~18400ms ~16s",
+		"wait 27200~36800ms ~32s",
+		"wait 54400~73600ms ~64s",
+		"wait 54400~73600ms ~64s",
+	}
+	got := b.ExampleSchedule(len(want))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %q, want: %q", got, want)
+	}
+	if b.delay != time.Millisecond*500 {
+		t.Fatalf("ExampleSchedule advanced the backoff to %v", b.delay)
+	}
+
+	noJitter := CoerceNew(WithInitialDelay(time.Second), WithJitterFactor(0))
+	if got := noJitter.ExampleSchedule(2); !reflect.DeepEqual(got, []string{"wait 1s", "wait 2s"}) {
+		t.Fatalf("got: %q", got)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	b := CoerceNew(
+		WithInitialDelay(0),
+		WithBaseDelay(time.Millisecond*100),
+		WithExponentialLimit(time.Millisecond*500),
+	)
+	b.computeDelay()
+	want := []time.Duration{0, 100, 200, 400, 800, 800}
+	for i := range want {
+		want[i] *= time.Millisecond
+	}
+	if got := b.Schedule(len(want)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	if b.delay != time.Millisecond*100 || b.Attempts() != 1 {
+		t.Fatalf("Schedule advanced the backoff to %v", b.delay)
+	}
+	if got := b.Schedule(-1); len(got) != 0 {
+		t.Fatalf("got: %v, want no delays", got)
+	}
+}
+
+func TestPeekRange(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Second), WithJitterFactor(0.5))
+	lo, hi := b.PeekRange()
+	if lo != time.Millisecond*750 || hi != time.Millisecond*1250 {
+		t.Fatalf("got: [%v, %v], want: [750ms, 1.25s]", lo, hi)
+	}
+	for i := 0; i < 100; i++ {
+		c := b.clone()
+		if d := c.computeDelay(); d < lo || d > hi {
+			t.Fatalf("delay %v outside of peeked range [%v, %v]", d, lo, hi)
+		}
+	}
+}
+
+func TestPeekJittered(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Second), WithJitterFactor(0.5), WithRandSource(rand.New(rand.NewSource(1))))
+	want := CoerceNew(WithInitialDelay(time.Second), WithJitterFactor(0.5), WithRandSource(rand.New(rand.NewSource(1))))
+	lo, hi := b.PeekRange()
+	for i := 0; i < 100; i++ {
+		if d := b.PeekJittered(); d < lo || d > hi {
+			t.Fatalf("delay %v outside of peeked range [%v, %v]", d, lo, hi)
+		}
+	}
+	if b.Attempts() != 0 || b.Next() != want.Next() {
+		t.Fatal("expected peeking not to disturb the backoff")
+	}
+}
+
+func TestTuneForDeadline(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Second))
+	if err := b.TuneForDeadline(time.Second*15, 5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.expLimit != time.Second*4 {
+		t.Fatalf("expected exp limit of 4s, got %v", b.expLimit)
+	}
+	if got := b.totalDelay(5); got != time.Second*15 {
+		t.Fatalf("expected schedule spanning 15s, got %v", got)
+	}
+
+	remaining := time.Second * 10
+	b = CoerceNew()
+	if err := b.TuneForDeadline(remaining, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := b.totalDelay(8); math.Abs(float64(got-remaining)) > float64(remaining)/4 {
+		t.Fatalf("expected schedule spanning ~%v, got %v", remaining, got)
+	}
+
+	// a deadline beyond the reach of the multiplier raises it
+	b = CoerceNew()
+	if err := b.TuneForDeadline(time.Hour, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.multiplier <= defaultMultiplier {
+		t.Fatalf("expected a raised multiplier, got %v", b.multiplier)
+	}
+	if got := b.totalDelay(3); math.Abs(float64(got-time.Hour)) > float64(time.Hour)/100 {
+		t.Fatalf("expected schedule spanning ~1h, got %v", got)
+	}
+
+	// the limit stays within the min and max delays
+	b = CoerceNew(WithMinDelay(time.Second))
+	if err := b.TuneForDeadline(time.Second, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.reconcile(false); err != nil {
+		t.Fatalf("expected a consistent configuration, got %v", err)
+	}
+	b = CoerceNew(WithMaxDelay(time.Second))
+	if err := b.TuneForDeadline(time.Hour, 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.expLimit != time.Second || b.multiplier != defaultMultiplier {
+		t.Fatalf("expected the max delay to cap the limit, got %v with multiplier %v", b.expLimit, b.multiplier)
+	}
+
+	// invalid inputs leave the backoff unchanged
+	b = CoerceNew()
+	if err := b.TuneForDeadline(0, 8); err == nil {
+		t.Fatalf("expected an error for no remaining time")
+	}
+	if err := b.TuneForDeadline(remaining, 0); err == nil {
+		t.Fatalf("expected an error for no attempts")
+	}
+	if b.expLimit != defaultExpLimit {
+		t.Fatalf("expected exp limit to be unchanged, got %v", b.expLimit)
+	}
+}
+
+func TestExpectedTotal(t *testing.T) {
+	ms := time.Millisecond
+	tests := map[string]struct {
+		b    *Backoff
+		n    int
+		want time.Duration
+	}{
+		"grows to the limit": {
+			CoerceNew(WithInitialDelay(ms*100), WithExponentialLimit(ms*400)), 5, ms * (100 + 200 + 400 + 400 + 400),
+		},
+		"limit of 0 never grows": {
+			CoerceNew(WithInitialDelay(ms*100), WithExponentialLimit(0)), 4, ms * 400,
+		},
+		"initial delay of 0": {
+			CoerceNew(WithInitialDelay(0), WithBaseDelay(ms*100), WithExponentialLimit(ms*200)), 4, ms * (0 + 100 + 200 + 200),
+		},
+		"no attempts": {CoerceNew(), 0, 0},
+		"saturates":   {CoerceNew(), math.MaxInt, math.MaxInt64},
+		"all zero":    {CoerceNew(WithInitialDelay(0), WithExponentialLimit(0)), 3, ms * 200},
+		"negative":    {CoerceNew(), -1, 0},
+	}
+	for name, tc := range tests {
+		tc.b.Next()
+		if got := tc.b.ExpectedTotal(tc.n); got != tc.want {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestMaxClients(t *testing.T) {
+	tests := map[string]struct {
+		b    *Backoff
+		rate float64
+		want int
+	}{
+		"without jitter": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithJitterFactor(0)),
+			100,
+			74,
+		},
+		"jitter shortens the worst-case delay": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second)),
+			100,
+			63,
+		},
+		"longer plateau supports more clients": {
+			CoerceNew(WithInitialDelay(time.Second*10), WithExponentialLimit(time.Second), WithJitterFactor(0)),
+			100,
+			741,
+		},
+		"jitter bounds that never shorten the delay": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithJitterBounds(0, 0.6)),
+			100,
+			74,
+		},
+		"no capacity": {
+			CoerceNew(),
+			0,
+			0,
+		},
+		"unbounded rate": {
+			CoerceNew(WithConstantFallback(1, 0)),
+			100,
+			0,
+		},
+		"unlimited growth": {
+			CoerceNew(WithExponentialLimit(math.MaxInt64)),
+			100,
+			math.MaxInt,
+		},
+		"full jitter has no lower edge": {
+			CoerceNew(WithJitterMode(JitterFull)),
+			100,
+			0,
+		},
+		"equal jitter halves the worst-case delay": {
+			CoerceNew(WithInitialDelay(time.Second*2), WithExponentialLimit(time.Second), WithJitterMode(JitterEqual)),
+			100,
+			74,
+		},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.b.MaxClients(tc.rate); got != tc.want {
+				t.Fatalf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+
+	// the expected retries per second of the clients, plus three standard
+	// deviations, fit within the downstream rate, and one more client does not
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithJitterFactor(0))
+	load := func(clients int) float64 {
+		mean := float64(clients) * b.SteadyStateRate()
+		return mean + 3*math.Sqrt(mean)
+	}
+	n := b.MaxClients(100)
+	if load(n) > 100 || load(n+1) <= 100 {
+		t.Fatalf("expected %d clients to be the most within 100 retries/s, got a load of %v", n, load(n))
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	// golden value for the default configuration
+	const defaultFingerprint = "0074cb3a19403ec866e659eff26f1b08"
+	if got := CoerceNew().Fingerprint(); got != defaultFingerprint {
+		t.Fatalf("default schedule changed, fingerprint: %v", got)
+	}
+
+	// identical behavior from different configurations
+	a := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*3))
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*4))
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Fatalf("expected equal fingerprints for identical schedules")
+	}
+
+	// progress does not affect the fingerprint
+	fp := a.Fingerprint()
+	a.computeDelay()
+	if a.Fingerprint() != fp {
+		t.Fatalf("expected fingerprint to be independent of progress")
+	}
+
+	// any observable difference changes the fingerprint
+	variants := []*Backoff{
+		CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*5)),
+		CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*3), WithJitterFactor(0.2)),
+		CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*3), WithLimitAsFloor()),
+		CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*3), WithConstantFallback(0.1, 0)),
+		CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*3), WithMinDelay(time.Second)),
+		CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*3), WithMaxDelay(time.Second*4)),
+	}
+	for i, v := range variants {
+		if v.Fingerprint() == fp {
+			t.Fatalf("variant %d: expected a different fingerprint", i)
+		}
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	within := time.Second * 3
+	b := CoerceNew(WithInitialDelay(time.Second))
+	got := b.CatchUp(5, within)
+	if len(got) != 5 {
+		t.Fatalf("expected 5 offsets, got %v", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Fatalf("offsets are not increasing: %v", got)
+		}
+		// preserves the relative exponential spacing
+		if i > 1 {
+			gap, prev := got[i]-got[i-1], got[i-1]-got[i-2]
+			if ratio := float64(gap) / float64(prev); math.Abs(ratio-2) > 0.001 {
+				t.Fatalf("expected spacing to double, got ratio %v in %v", ratio, got)
+			}
+		}
+	}
+	if got[len(got)-1] != within {
+		t.Fatalf("expected final offset of %v, got %v", within, got[len(got)-1])
+	}
+	if b.delay != time.Second {
+		t.Fatalf("CatchUp advanced the backoff to %v", b.delay)
+	}
+
+	if got := b.CatchUp(0, within); got != nil {
+		t.Fatalf("expected nil for no missed attempts, got %v", got)
+	}
+}
+
+func TestTimingEntropy(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Second))
+	// 300 equally likely 1ms buckets
+	if got, want := b.TimingEntropy(0), math.Log2(300); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	// the jitter band doubles with the delay
+	if got, want := b.TimingEntropy(1), math.Log2(600); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+
+	// a band straddling two buckets unevenly
+	if got, want := uniformEntropy(0.5, 2, 1), -(1.0/3)*math.Log2(1.0/3)-(2.0/3)*math.Log2(2.0/3); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+
+	tests := map[string]*Backoff{
+		"no jitter":              CoerceNew(WithJitterFactor(0)),
+		"sub-millisecond jitter": CoerceNew(WithInitialDelay(time.Microsecond * 100)),
+		"immediate first retry":  CoerceNew(WithInitialDelay(0)),
+	}
+	for name, b := range tests {
+		if got := b.TimingEntropy(0); got != 0 {
+			t.Fatalf("%s: expected 0 bits, got %v", name, got)
+		}
+	}
+}
+
+func TestEffectiveJitterResolution(t *testing.T) {
+	tests := map[string]struct {
+		b    *Backoff
+		want time.Duration
+	}{
+		"sub-millisecond band": {CoerceNew(WithBaseDelay(time.Millisecond)), time.Microsecond * 300},
+		"defaults":             {CoerceNew(), time.Millisecond * 30},
+		"no jitter":            {CoerceNew(WithJitterFactor(0)), 0},
+	}
+	for name, tc := range tests {
+		if got := tc.b.EffectiveJitterResolution(); got != tc.want {
+			t.Fatalf("%s: got: %v, want: %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestAttemptsWithinBudget(t *testing.T) {
+	// 1s, 2s, 4s, 8s, 8s, ...
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*8))
+	budget := time.Second * 35
+	deterministic := b.attemptsWithin(budget, func(lo, hi float64) float64 { return (lo + hi) / 2 })
+	if deterministic != 6 {
+		t.Fatalf("expected 6 deterministic attempts, got %d", deterministic)
+	}
+	lo, hi := b.AttemptsWithinBudget(budget)
+	if lo != 5 || hi != 7 {
+		t.Fatalf("got: [%d, %d], want: [5, 7]", lo, hi)
+	}
+
+	// no jitter collapses the range
+	b = CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*8), WithJitterFactor(0))
+	if lo, hi := b.AttemptsWithinBudget(budget); lo != 6 || hi != 6 {
+		t.Fatalf("got: [%d, %d], want: [6, 6]", lo, hi)
+	}
+
+	// capped by max attempts
+	b = CoerceNew(WithInitialDelay(time.Second), WithMaxAttempts(3))
+	if lo, hi := b.AttemptsWithinBudget(time.Hour); lo != 3 || hi != 3 {
+		t.Fatalf("got: [%d, %d], want: [3, 3]", lo, hi)
+	}
+
+	// clamped by the min and max delays: 5s, 5s, 5s, 8s, 8s and 1s, 2s, 2s, ...
+	b = CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*8), WithJitterFactor(0), WithMinDelay(time.Second*5))
+	if lo, hi := b.AttemptsWithinBudget(budget); lo != 5 || hi != 5 {
+		t.Fatalf("got: [%d, %d], want: [5, 5]", lo, hi)
+	}
+	b = CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*8), WithJitterFactor(0), WithMaxDelay(time.Second*2))
+	if lo, hi := b.AttemptsWithinBudget(budget); lo != 18 || hi != 18 {
+		t.Fatalf("got: [%d, %d], want: [18, 18]", lo, hi)
+	}
+
+	// unbounded when delays of 0 repeat forever
+	if _, hi := ReplayBackoff(nil).AttemptsWithinBudget(time.Second); hi != math.MaxInt {
+		t.Fatalf("expected unbounded attempts, got %d", hi)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*8))
+	if got, want := b.Sparkline(6), "▁▂▄███"; got != want {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	for _, n := range []int{0, 1, 5, 40} {
+		if got := utf8.RuneCountInString(b.Sparkline(n)); got != n {
+			t.Fatalf("expected %d bars, got %d", n, got)
+		}
+	}
+	if got := b.Sparkline(1); got != "█" {
+		t.Fatalf("single value, got: %v", got)
+	}
+	if got := ReplayBackoff(nil).Sparkline(3); got != "▁▁▁" {
+		t.Fatalf("all zero, got: %v", got)
+	}
+	if got := b.Sparkline(-1); got != "" {
+		t.Fatalf("expected empty sparkline, got: %v", got)
+	}
+}
+
+func TestWorstCaseDelay(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*4))
+	noJitter := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*4), WithJitterFactor(0))
+	for n, want := range []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 4} {
+		if got := b.DelayAt(n); got != want {
+			t.Fatalf("DelayAt(%d), got: %v, want: %v", n, got, want)
+		}
+		if got := noJitter.WorstCaseDelay(n); got != noJitter.DelayAt(n) {
+			t.Fatalf("WorstCaseDelay(%d) without jitter, got: %v, want: %v", n, got, want)
+		}
+		if got, upper := b.WorstCaseDelay(n), want+want*15/100; got != upper {
+			t.Fatalf("WorstCaseDelay(%d), got: %v, want: %v", n, got, upper)
+		}
+	}
+
+	// considers a longer constant fallback
+	b = CoerceNew(WithInitialDelay(time.Second), WithJitterFactor(0), WithConstantFallback(0.1, time.Minute))
+	if got := b.WorstCaseDelay(0); got != time.Minute {
+		t.Fatalf("got: %v, want: %v", got, time.Minute)
+	}
+
+	if got := b.WorstCaseDelay(-1); got != 0 {
+		t.Fatalf("expected 0 for a negative attempt, got: %v", got)
+	}
+}
+
+func TestAllocateBudget(t *testing.T) {
+	total := time.Second * 10
+	b := CoerceNew(WithInitialDelay(time.Millisecond))
+	phases := b.AllocateBudget(total, []float64{1, 2, 3.5})
+	if len(phases) != 3 {
+		t.Fatalf("expected 3 phases, got %d", len(phases))
+	}
+	var sum time.Duration
+	for _, p := range phases {
+		sum += p.maxElapsed
+		if p.initDelay != b.initDelay {
+			t.Fatalf("expected phases configured like the original")
+		}
+	}
+	if sum != total {
+		t.Fatalf("expected budgets to add up to %v, got %v", total, sum)
+	}
+	if got, want := phases[1].maxElapsed, time.Duration(float64(total)*2/6.5); got-want > 1 || want-got > 1 {
+		t.Fatalf("expected proportional budget of %v, got %v", want, got)
+	}
+
+	// a phase is done once its budget has elapsed
+	now := time.Unix(1700000000, 0)
+	p := phases[0]
+	p.clock = nowClock(func() time.Time { return now })
+	p.computeDelay()
+	if p.Done() {
+		t.Fatalf("done before the budget elapsed")
+	}
+	now = now.Add(p.maxElapsed)
+	if !p.Done() {
+		t.Fatalf("expected done once the budget elapsed")
+	}
+
+	for _, weights := range [][]float64{nil, {1, 0}, {1, -1}, {math.NaN()}} {
+		if got := b.AllocateBudget(total, weights); got != nil {
+			t.Fatalf("weights %v: expected nil, got %v", weights, got)
+		}
+	}
+}
+
+func TestExpectedUntilSuccess(t *testing.T) {
+	// 1s, 2s, 4s, 4s, ...
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*4), WithJitterFactor(0))
+	limited := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second*4), WithJitterFactor(0), WithMaxAttempts(2))
+
+	tests := map[string]struct {
+		b           *Backoff
+		failureProb float64
+		wantRetries float64
+		wantTime    time.Duration
+	}{
+		// 0.5*1s + 0.25*2s + (0.125+0.0625+...)*4s
+		"unlimited": {b, 0.5, 1, time.Second * 2},
+		// 0.5*1s + 0.25*2s
+		"max attempts": {limited, 0.5, 0.75, time.Second},
+		"never fails":  {b, 0, 0, 0},
+		"always fails": {b, 1, math.NaN(), 0},
+		"negative":     {b, -0.1, math.NaN(), 0},
+	}
+	for name, tc := range tests {
+		got := tc.b.ExpectedRetriesUntilSuccess(tc.failureProb)
+		if math.IsNaN(tc.wantRetries) != math.IsNaN(got) || math.Abs(got-tc.wantRetries) > 1e-9 {
+			t.Fatalf("%s: got: %v retries, want: %v", name, got, tc.wantRetries)
+		}
+		if got := tc.b.ExpectedTimeUntilSuccess(tc.failureProb); got != tc.wantTime {
+			t.Fatalf("%s: got: %v, want: %v", name, got, tc.wantTime)
+		}
+	}
+}
@@ -3,7 +3,7 @@ Package backoff provides a backoff object that makes it easy to consistently
 apply exponential backoff with jitter.
 
 	Ex.
-	b, err := NewBackoff(
+	b, err := New(
 	  WithInitialDelay(0),
 	  WithBaseDelay(time.Millisecond * 500),
 	  WithExponentialLimit(time.Second * 60), // stop growing exponentially after 1 min
@@ -17,17 +17,24 @@ apply exponential backoff with jitter.
 	b.Sleep() // wait 1700~2300ms 		~2s
 	b.Sleep() // wait 3400-4600ms 		~4s
 	b.Sleep() // wait 6800~9200ms 		~8s
-	b.Sleep() // wait 1360~1840ms 		~16s
+	b.Sleep() // wait 13600~18400ms 	~16s
 	b.Sleep() // wait 27200~36800ms 	~32s
 	b.Sleep() // wait 54400~73600ms 	~64s
 	b.Sleep() // wait 54400~73600ms 	~64s (stopped growing, jitter still applied)
+
+The schedule for any configuration can be listed in this format with
+ExampleSchedule.
 */
 package backoff
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"math"
 	"math/rand"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -36,11 +43,145 @@ type backoffOption func(*Backoff, bool) error
 // Backoff provides exponential backoff with jitter. By default, the initial
 // backoff is 100ms, the jitter factor is 0.3 (so +/- 15%), and exponential growth
 // stops once the backoff reaches 3 minutes.
+//
+// A Backoff is safe for concurrent use by multiple goroutines. Every method
+// holds an internal lock while it reads or advances the state, so concurrent
+// callers share one sequence of delays, each advancing it by one round.
+// Separate calls are not atomic together, e.g. the attempt count read after
+// Next may include other callers' rounds; Advance returns both atomically.
+// The callback set by WithDelayOverride runs while the lock is held, so it must
+// not call methods of the backoff, except Metadata, whereas the callback set by
+// WithOnRetry runs without it.
 type Backoff struct {
-	delay        time.Duration
+	mu sync.Mutex
+	settings
+	progress
+
+	attempts      int           // the number of sleeps performed
+	start         time.Time     // the time of the first round
+	plateauRounds int           // the number of consecutive rounds at the plateau
+	lastDelay     time.Duration // the last delay, for decorrelated jitter
+	forced        bool          // whether the next delay is forcedDelay
+	forcedDelay   time.Duration // the next delay, before jitter, see Override
+	spreadDone    bool          // whether the startup spread has been applied
+	delaySum      time.Duration // the sum of the delays, see Stats
+	finalDelay    time.Duration // the most recent delay, see Stats
+	scriptNext    int           // the index of the next scripted delay
+
+	// whether WithJitterFactor was given, to tell an explicit jitter factor
+	// apart from the default, see reconcile
+	jitterFactorSet bool
+
+	// caller data, with its own lock so that callbacks can read it
+	metaMu sync.Mutex
+	meta   map[string]any
+}
+
+// settings holds the configuration of a Backoff.
+type settings struct {
+	// optional name identifying the backoff, e.g. in logs, see WithName
+	name string
+
+	initDelay    time.Duration
 	baseDelay    time.Duration
 	expLimit     time.Duration
 	jitterFactor float64
+	multiplier   float64
+	growth       GrowthStrategy
+	jitterMode   JitterStrategy
+
+	// limit on the number of growth steps (0 = none), see WithMaxDoublings
+	maxDoublings int
+
+	// fixed jitter, replacing the jitter factor (0 = none), see
+	// WithAbsoluteJitter
+	absJitter time.Duration
+
+	// limit on how far jitter moves the delay (0 = none), see
+	// WithMaxJitterAbsolute
+	maxJitter time.Duration
+
+	// asymmetric jitter, as fractions of the delay, replacing the jitter
+	// factor, see WithJitterBounds
+	jitterBounds          bool
+	jitterLow, jitterHigh float64
+
+	// limit of the random offset added to the very first delay (0 = none), see
+	// WithStartupSpread
+	startupSpread time.Duration
+
+	// optional constant fallback, chosen at random per round
+	fallbackProb  float64
+	fallbackDelay time.Duration
+
+	// jitter only increases the delay once at the exponential limit
+	limitAsFloor bool
+
+	// growth continues linearly beyond the exponential limit, up to the max
+	// delay, see WithSoftLimit
+	softLimit bool
+
+	// growth after the initial delay always starts from the base delay
+	baseAlways bool
+
+	// recorded delays that replace growth entirely, see ReplayBackoff
+	replay []time.Duration
+
+	// delays used exactly for the first rounds, see WithSchedule
+	script []time.Duration
+
+	// hard floor and cap on the delay, after jitter (0 = none), see WithMinDelay
+	// and WithMaxDelay
+	minDelay time.Duration
+	maxDelay time.Duration
+
+	// limits on the number of sleeps, and on the time since the first round
+	// (0 = no limit)
+	maxAttempts int
+	maxElapsed  time.Duration
+
+	// what Sleep does once the limits are exhausted
+	onExhausted ExhaustedPolicy
+
+	// optional recorder of every computed delay, see WithRecorder
+	recorder *Recorder
+
+	// optional ring buffer of recent rounds, see WithHistory
+	history *history
+
+	// moving average of observed latency, that the base delay tracks
+	latencyAvg        float64
+	latencyMultiplier float64
+
+	// SleepContext advances even if the context is already done
+	advanceOnCancel bool
+
+	// optional callback that replaces the planned delay, before jitter
+	override func(attempt int, planned time.Duration) time.Duration
+
+	// optional callback that observes each round, see WithOnRetry
+	onRetry func(name string, attempt int, delay time.Duration)
+
+	// optional long pause after a number of consecutive rounds at the plateau
+	pauseAfter int
+	pauseDelay time.Duration
+
+	// source of randomness for jitter, owned by this backoff
+	rng *rand.Rand
+
+	// optional global slots that delays are aligned to, see WithGlobalSlots
+	slot       time.Duration
+	slotOffset time.Duration
+
+	clock Clock
+}
+
+// progress holds the state of a Backoff that advances with each round.
+type progress struct {
+	delay  time.Duration // the next delay, before jitter
+	prev   time.Duration // the delay before it, for Fibonacci growth
+	rounds int           // the number of rounds since the initial delay
+	grown  int           // the number of growth steps, for WithMaxDoublings
 }
 
 var (
@@ -49,14 +190,37 @@ var (
 	defaultExpLimit  = time.Minute * 3
 )
 
-const defaultJitterFactor = 0.3
+// The errors returned by New, wrapped with the details, when the core options
+// are invalid, so that callers can detect specific misconfigurations with
+// errors.Is, even in the joined error.
+var (
+	ErrInvalidInitialDelay = errors.New("backoff: invalid initial delay")
+	ErrInvalidBaseDelay    = errors.New("backoff: invalid base delay")
+	ErrInvalidExpLimit     = errors.New("backoff: invalid exponential limit")
+	ErrInvalidJitterFactor = errors.New("backoff: invalid jitter factor")
+)
+
+const (
+	defaultJitterFactor      = 0.3
+	defaultMultiplier        = 2.0
+	defaultLatencyMultiplier = 1.0
+)
 
 func defaultBackoff() *Backoff {
 	return &Backoff{
-		delay:        defaultInitDelay,
-		baseDelay:    defaultBaseDelay,
-		expLimit:     defaultExpLimit,
-		jitterFactor: defaultJitterFactor,
+		settings: settings{
+			initDelay:    defaultInitDelay,
+			baseDelay:    defaultBaseDelay,
+			expLimit:     defaultExpLimit,
+			jitterFactor: defaultJitterFactor,
+			multiplier:   defaultMultiplier,
+
+			latencyMultiplier: defaultLatencyMultiplier,
+
+			rng:   rand.New(rand.NewSource(rand.Int63())),
+			clock: realClock{},
+		},
+		progress: progress{delay: defaultInitDelay},
 	}
 }
 
@@ -71,6 +235,7 @@ func New(options ...backoffOption) (*Backoff, error) {
 	for i := 0; i < len(options); i++ {
 		errs = errors.Join(errs, options[i](b, false))
 	}
+	errs = errors.Join(errs, b.reconcile(false))
 	if errs != nil {
 		return nil, errs
 	}
@@ -86,10 +251,79 @@ func CoerceNew(options ...backoffOption) *Backoff {
 	for i := 0; i < len(options); i++ {
 		options[i](b, true)
 	}
+	b.reconcile(true)
 
 	return b
 }
 
+// reconcile checks the options that constrain each other, once all have been
+// applied, coercing inconsistent values if coerce is set.
+func (b *Backoff) reconcile(coerce bool) error {
+	var errs error
+	if b.absJitter > 0 || b.jitterBounds {
+		if b.jitterFactorSet && b.jitterFactor > 0 && !coerce {
+			errs = errors.Join(errs, fmt.Errorf("%w: the jitterFactor must be 0 with absolute jitter or jitter bounds", ErrInvalidJitterFactor))
+		} else {
+			// replace the default proportional jitter, or assume caller
+			// wanted the absolute jitter or jitter bounds
+			b.jitterFactor = 0
+		}
+	}
+
+	if b.jitterBounds && b.absJitter > 0 {
+		if !coerce {
+			errs = errors.Join(errs, fmt.Errorf("%w: the absolute jitter must be 0 with jitter bounds", ErrInvalidJitterFactor))
+		} else {
+			// assume caller wanted the jitter bounds
+			b.absJitter = 0
+		}
+	}
+
+	if b.softLimit && b.maxDelay == 0 {
+		if !coerce {
+			errs = errors.Join(errs, errors.New("the soft limit requires a max delay"))
+		} else {
+			// assume caller wanted a hard limit
+			b.softLimit = false
+		}
+	}
+
+	ceiling := b.expLimit
+	if b.maxDelay > 0 {
+		ceiling = min(ceiling, b.maxDelay)
+	}
+	if b.minDelay > ceiling {
+		if !coerce {
+			errs = errors.Join(errs, errors.New("the min delay must be <= the exponential limit and the max delay"))
+		} else {
+			// assume caller wanted the floor as high as is consistent
+			b.minDelay = ceiling
+		}
+	}
+	return errs
+}
+
+// WithTemplate configuration BackoffOption copies the configuration of another
+// backoff, so that the options after it only need to set what differs, e.g.
+// to derive specialized variants from a default. It copies the configured
+// initial delay, not the current progress of the template. It replaces the
+// effect of any options before it, so it should be the first option.
+func WithTemplate(other *Backoff) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if other == nil {
+			if !coerce {
+				return errors.New("the template must not be nil")
+			}
+			// keep default values
+			return nil
+		}
+		c := other.Clone()
+		b.settings = c.settings
+		b.progress = c.progress
+		return nil
+	}
+}
+
 // WithInitialDelay configuration BackoffOption allows customization of the
 // initial backoff delay (before jitter). It is safe to set this to 0, allowing
 // the first retry to occur immediately, then after the first delay it will
@@ -99,13 +333,38 @@ func WithInitialDelay(d time.Duration) backoffOption {
 	return func(b *Backoff, coerce bool) error {
 		if d >= 0 {
 			b.delay = d
+			b.initDelay = d
 			return nil
 		}
 		if !coerce {
-			return errors.New("the initial delay must be >= 0")
+			return fmt.Errorf("%w: the initial delay must be >= 0", ErrInvalidInitialDelay)
 		}
 		// assume caller wanted immediate initial retry
 		b.delay = 0
+		b.initDelay = 0
+		return nil
+	}
+}
+
+// WithStartupSpread configuration BackoffOption adds a one-time, uniformly
+// random offset in [0, spread] to the first delay of the backoff, after
+// jitter. When many workers start at the same instant, e.g. after a deploy,
+// this decorrelates their first retries, which per-round jitter alone does
+// not, since every worker starts from the same initial delay. Later rounds
+// follow the normal schedule, and the offset is not applied again after a
+// Reset, though each clone applies its own. The spread must be >= 0, and the
+// default is 0, meaning that there is no offset.
+func WithStartupSpread(spread time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if spread >= 0 {
+			b.startupSpread = spread
+			return nil
+		}
+		if !coerce {
+			return errors.New("the startup spread must be >= 0")
+		}
+		// assume caller wanted no startup spread
+		b.startupSpread = 0
 		return nil
 	}
 }
@@ -124,7 +383,7 @@ func WithBaseDelay(d time.Duration) backoffOption {
 
 		}
 		if !coerce {
-			return errors.New("the base delay must be > 0")
+			return fmt.Errorf("%w: the base delay must be > 0", ErrInvalidBaseDelay)
 		}
 
 		// keep the default value
@@ -143,7 +402,7 @@ func WithExponentialLimit(d time.Duration) backoffOption {
 			return nil
 		}
 		if !coerce {
-			return errors.New("the exponential backoff limit must be >= 0")
+			return fmt.Errorf("%w: the exponential backoff limit must be >= 0", ErrInvalidExpLimit)
 		}
 		// assume caller wanted zero exponential growth in the backoff
 		b.expLimit = 0
@@ -151,18 +410,43 @@ func WithExponentialLimit(d time.Duration) backoffOption {
 	}
 }
 
+// WithMaxDoublings configuration BackoffOption stops the backoff delay growing
+// after the given number of growth steps (e.g. doublings, with the default
+// multiplier), whatever the resulting delay, for callers who think of the cap
+// as "double at most n times". The step from an initial delay of 0 to the base
+// delay does not count. It coexists with the exponential limit: growth stops
+// at whichever is reached first. The count must be >= 0, and the default is 0,
+// meaning that only the exponential limit applies (use an exponential limit of
+// 0 to prevent growth entirely).
+func WithMaxDoublings(n int) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if n >= 0 {
+			b.maxDoublings = n
+			return nil
+		}
+		if !coerce {
+			return errors.New("the max doublings must be >= 0")
+		}
+		// assume caller wanted no limit on the doublings
+		b.maxDoublings = 0
+		return nil
+	}
+}
+
 // WithJitterFactor configuration BackoffOption allows customization of the
-// jitter factor. The value must be in the range [0,1). Jitter is applied
+// jitter factor. The value must be in the range [0,1]. Jitter is applied
 // uniformly randomly about the backoff delay, so 0.3 represents the backoff
-// delay being adjusted by +/- 15%. The default is 0.3.
+// delay being adjusted by +/- 15%, and the widest spread, 1, represents it
+// being adjusted by +/- 50%. The default is 0.3.
 func WithJitterFactor(jitterFactor float64) backoffOption {
 	return func(b *Backoff, coerce bool) error {
-		if jitterFactor >= 0 && jitterFactor < 1.0 {
+		if jitterFactor >= 0 && jitterFactor <= 1.0 {
 			b.jitterFactor = jitterFactor
+			b.jitterFactorSet = true
 			return nil
 		}
 		if !coerce {
-			return errors.New("the jitterFactor must be in the range [0,1)")
+			return fmt.Errorf("%w: the jitterFactor must be in the range [0,1]", ErrInvalidJitterFactor)
 		}
 		if jitterFactor < 0 {
 			// assume caller wanted to disable jitter
@@ -175,30 +459,1432 @@ func WithJitterFactor(jitterFactor float64) backoffOption {
 	}
 }
 
-// Sleep pauses execution on the current thread. The duration of the sleep
+// WithAbsoluteJitter configuration BackoffOption applies jitter as a fixed
+// spread, rather than in proportion to the delay, so each delay is adjusted by
+// a uniformly random amount in [-jitter, +jitter], e.g. +/- 50ms regardless of
+// the size of the delay. Delays are never made negative by the jitter. It
+// replaces the default jitter factor, and so cannot be combined with a
+// non-zero jitter factor set by WithJitterFactor. Like the jitter factor, it
+// only applies to the symmetric jitter mode. The value must be >= 0, and the
+// default is 0, meaning that the jitter factor is used.
+func WithAbsoluteJitter(jitter time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if jitter < 0 {
+			if !coerce {
+				return errors.New("the absolute jitter must be >= 0")
+			}
+			// keep default value
+			return nil
+		}
+		b.absJitter = jitter
+		return nil
+	}
+}
+
+// WithMaxJitterAbsolute configuration BackoffOption caps how far jitter can
+// move each delay, in absolute terms, so that proportional jitter on large
+// delays still decorrelates clients, without an unbounded spread, e.g. +/- 27s
+// on a 3 minute delay with the default jitter factor becomes +/- 5s with a cap
+// of 5s. The cap applies to the jitter band of every jitter mode except
+// JitterDecorrelated, after the band is computed, so it only narrows the
+// band on delays large enough to exceed it. The cap must be >= 0, and the
+// default is 0, meaning that there is no cap.
+func WithMaxJitterAbsolute(d time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if d >= 0 {
+			b.maxJitter = d
+			return nil
+		}
+		if !coerce {
+			return errors.New("the max absolute jitter must be >= 0")
+		}
+		// assume caller wanted no cap on the jitter
+		b.maxJitter = 0
+		return nil
+	}
+}
+
+// WithJitterBounds configuration BackoffOption applies jitter asymmetrically,
+// multiplying each delay by a uniformly random value in [1+lowFrac,
+// 1+highFrac], e.g. WithJitterBounds(0, 0.3) to wait as scheduled or up to 30%
+// longer, but never retry sooner than scheduled, or WithJitterBounds(-0.3, 0)
+// to never wait longer. It generalizes the jitter factor, which is equivalent
+// to bounds of -jitterFactor/2 and +jitterFactor/2. Like WithAbsoluteJitter,
+// it replaces the default jitter factor, cannot be combined with a non-zero
+// jitter factor or absolute jitter, and only applies to the symmetric jitter
+// mode. The lowFrac must be >= -1, so that delays are never negative, and <=
+// highFrac. The default is no bounds, meaning that the jitter factor is used.
+func WithJitterBounds(lowFrac, highFrac float64) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if !(lowFrac >= -1 && lowFrac <= highFrac) || math.IsInf(highFrac, 1) {
+			if !coerce {
+				return errors.New("the jitter bounds must satisfy -1 <= lowFrac <= highFrac")
+			}
+			// keep default value
+			return nil
+		}
+		b.jitterBounds, b.jitterLow, b.jitterHigh = true, lowFrac, highFrac
+		return nil
+	}
+}
+
+// WithGrowth configuration BackoffOption selects how the backoff delay grows
+// in each round, until it reaches the exponential limit, e.g. GrowthFibonacci.
+// The multiplier only applies to GrowthExponential. The default is
+// GrowthExponential.
+func WithGrowth(growth GrowthStrategy) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if _, ok := growthStrategyNames[growth]; ok {
+			b.growth = growth
+			return nil
+		}
+		if !coerce {
+			return errors.New("unknown growth strategy")
+		}
+		// keep default value
+		b.growth = GrowthExponential
+		return nil
+	}
+}
+
+// WithJitterMode configuration BackoffOption selects how jitter is applied to
+// the backoff delay, e.g. JitterFull for "full jitter". When the limit is used
+// as a floor (see WithLimitAsFloor), delays at the limit still only ever
+// increase, by up to the jitter factor. The default is JitterSymmetric.
+func WithJitterMode(mode JitterStrategy) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if _, ok := jitterStrategyNames[mode]; ok {
+			b.jitterMode = mode
+			return nil
+		}
+		if !coerce {
+			return errors.New("unknown jitter mode")
+		}
+		// keep default value
+		b.jitterMode = JitterSymmetric
+		return nil
+	}
+}
+
+// WithRandSource configuration BackoffOption sets the source of randomness used
+// for jitter, e.g. a source with a fixed seed, to make the delays reproducible
+// in tests. The backoff only uses it while holding its lock, so it must not be
+// shared with other users, and backoffs derived from this one (e.g. by Clone
+// or a Pool) use sources seeded from it. By default, each backoff has its own
+// source, seeded from the global one.
+func WithRandSource(r *rand.Rand) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if r != nil {
+			b.rng = r
+			return nil
+		}
+		if !coerce {
+			return errors.New("the rand source must not be nil")
+		}
+		// keep default value
+		return nil
+	}
+}
+
+// WithJitterSeed configuration BackoffOption gives the backoff its own source
+// of randomness for jitter, seeded with the given seed, so that its delays are
+// reproducible, e.g. to assert exact jittered delays in tests, or to spread a
+// fleet deterministically by deriving the seed from the host. It is a
+// shorthand for WithRandSource with a new seeded source. Since the source is
+// owned by the backoff, it needs no locking of its own, unlike a source that
+// is passed to WithRandSource and also used elsewhere, which must then be
+// synchronized by the caller.
+func WithJitterSeed(seed int64) backoffOption {
+	return WithRandSource(rand.New(rand.NewSource(seed)))
+}
+
+// WithMultiplier configuration BackoffOption allows customization of the
+// factor by which the backoff delay grows in each round, e.g. 1.5 for gentler
+// growth, or 3 for more aggressive growth. The multiplier must be > 1, and the
+// default is 2.
+func WithMultiplier(m float64) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if m > 1 && !math.IsInf(m, 1) {
+			b.multiplier = m
+			return nil
+		}
+		if !coerce {
+			return errors.New("the multiplier must be > 1")
+		}
+		// keep default value
+		b.multiplier = defaultMultiplier
+		return nil
+	}
+}
+
+// WithMinDelay configuration BackoffOption sets a floor on the delay, applied
+// after jitter (and any constant fallback, plateau pause, or delay override),
+// so the backoff never retries sooner than it, e.g. with full jitter, which can
+// otherwise produce delays near 0. The min delay must be >= 0, and no greater
+// than the exponential limit (or the max delay, if any). The default is 0,
+// meaning that there is no floor.
+func WithMinDelay(d time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if d >= 0 {
+			b.minDelay = d
+			return nil
+		}
+		if !coerce {
+			return errors.New("the min delay must be >= 0")
+		}
+		// assume caller wanted no floor
+		b.minDelay = 0
+		return nil
+	}
+}
+
+// WithMaxDelay configuration BackoffOption sets a hard ceiling on the delay.
+// Unlike the exponential limit, which only stops growth, it also caps the
+// delay after jitter (and any constant fallback, plateau pause, or delay
+// override), so the backoff never waits longer than it, e.g. to stay within
+// a downstream timeout. The max delay must be >= 0, and the default is 0,
+// meaning that there is no cap.
+func WithMaxDelay(d time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if d >= 0 {
+			b.maxDelay = d
+			return nil
+		}
+		if !coerce {
+			return errors.New("the max delay must be >= 0")
+		}
+		// assume caller wanted no cap
+		b.maxDelay = 0
+		return nil
+	}
+}
+
+// WithBaseDelayAlways configuration BackoffOption makes growth after the
+// initial delay always start from the base delay, rather than from twice the
+// initial delay. This decouples the first wait from the starting point of the
+// exponential growth, e.g. an initial delay of 50ms and a base delay of 500ms
+// give delays of 50ms, 500ms, 1s, 2s, and so on. By default, the base delay is
+// only used if the initial delay is 0.
+func WithBaseDelayAlways() backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		b.baseAlways = true
+		return nil
+	}
+}
+
+// WithLimitAsFloor configuration BackoffOption makes the exponential limit act
+// as a floor once the backoff has stopped growing. By default jitter is
+// centered on the delay, so about half the delays at the plateau fall below
+// the limit. With this option, jitter at the plateau is applied only upward,
+// across a band of the same width, so the delay never falls below the limit.
+func WithLimitAsFloor() backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		b.limitAsFloor = true
+		return nil
+	}
+}
+
+// WithSoftLimit configuration BackoffOption makes the exponential limit a soft
+// cap: once the backoff reaches it (or the max doublings), the delay keeps
+// growing, but only linearly, by the base delay each round, until it reaches
+// the max delay, modelling strategies that back off fast, then creep. It
+// requires a max delay (see WithMaxDelay), since growth would otherwise never
+// stop.
+func WithSoftLimit() backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		b.softLimit = true
+		return nil
+	}
+}
+
+// WithConstantFallback configuration BackoffOption makes each round, with
+// probability prob, use the constant delay (before jitter) instead of the
+// exponential delay. The exponential growth state still advances as usual, so
+// rounds that do not fall back continue to follow the exponential schedule.
+// The probability must be in the range [0,1], and the constant delay must be
+// >= 0. By default there is no constant fallback.
+func WithConstantFallback(prob float64, constant time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if prob >= 0 && prob <= 1.0 && constant >= 0 {
+			b.fallbackProb = prob
+			b.fallbackDelay = constant
+			return nil
+		}
+		if !coerce {
+			return errors.New("the constant fallback probability must be in the range [0,1] and the constant delay must be >= 0")
+		}
+		// clamp both to the nearest valid values
+		b.fallbackProb = math.Min(math.Max(prob, 0), 1.0)
+		b.fallbackDelay = max(constant, 0)
+		return nil
+	}
+}
+
+// WithSchedule configuration BackoffOption scripts the delays of the first
+// rounds: they are used exactly as listed, in order, without jitter, min and
+// max delays, or growth, e.g. to assert precise retry timing in tests, or to
+// script a custom curve. Once the list is exhausted, the backoff behaves
+// normally, starting from its initial delay. Reset restarts the list. Among
+// the analysis methods, only Schedule and Fingerprint include the scripted
+// delays. The delays must be >= 0, and the default is none.
+func WithSchedule(delays ...time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		script := make([]time.Duration, len(delays))
+		for i, d := range delays {
+			if d < 0 {
+				if !coerce {
+					return errors.New("the scheduled delays must be >= 0")
+				}
+				// assume caller wanted an immediate retry
+				d = 0
+			}
+			script[i] = d
+		}
+		b.script = script
+		return nil
+	}
+}
+
+// ReplayBackoff returns a backoff that yields the recorded delays in order,
+// and then keeps yielding the last one, with no growth or jitter. This can be
+// used to reproduce the exact timing of an incident from logged delays in a
+// deterministic test. If no delays are given, every delay is 0.
+func ReplayBackoff(delays []time.Duration) *Backoff {
+	if len(delays) == 0 {
+		delays = []time.Duration{0}
+	}
+	b := defaultBackoff()
+	b.replay = append([]time.Duration(nil), delays...)
+	b.delay = b.replay[0]
+	b.initDelay = b.replay[0]
+	b.jitterFactor = 0
+	return b
+}
+
+const (
+	tcpInitialRTO = time.Second      // RFC 6298 section 2.1
+	tcpMaxRTO     = time.Second * 60 // RFC 6298 section 2.5
+)
+
+// TCPLike returns a backoff that mirrors TCP's retransmission timeout (RTO)
+// backoff, as specified by RFC 6298. The first delay is initialRTO (the RFC's
+// initial RTO of 1s, if initialRTO is not positive), each timeout doubles the
+// delay (section 5.5), and the delay is capped at 60s (section 2.5, which
+// allows a maximum of at least 60s). There is no jitter, since TCP does not
+// jitter the RTO.
+func TCPLike(initialRTO time.Duration) *Backoff {
+	if initialRTO <= 0 {
+		initialRTO = tcpInitialRTO
+	}
+	initialRTO = min(initialRTO, tcpMaxRTO)
+
+	b := defaultBackoff()
+	b.initDelay, b.baseDelay, b.delay = initialRTO, initialRTO, initialRTO
+	b.expLimit, b.maxDelay = tcpMaxRTO, tcpMaxRTO
+	b.jitterFactor = 0
+	return b
+}
+
+// WithMaxAttempts configuration BackoffOption limits the number of times the
+// backoff can be performed (i.e. the number of sleeps, or calls to Next). Once
+// the limit is reached, Done reports true, so a retry loop can be written as
+// `for !b.Done() { ...; b.Sleep() }`, and further sleeps return immediately
+// (unless WithOnExhausted is used). Peeking at the next delay never counts as
+// an attempt. The limit must be >= 0, and the default is 0, meaning that there
+// is no limit.
+func WithMaxAttempts(n int) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if n >= 0 {
+			b.maxAttempts = n
+			return nil
+		}
+		if !coerce {
+			return errors.New("the max attempts must be >= 0")
+		}
+		// assume caller wanted no limit
+		b.maxAttempts = 0
+		return nil
+	}
+}
+
+// WithLatencyMultiplier configuration BackoffOption sets the multiple of the
+// average observed latency (see ObserveLatency) that the base delay is set to.
+// The multiplier must be > 0, and the default is 1.
+func WithLatencyMultiplier(m float64) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if m > 0 {
+			b.latencyMultiplier = m
+			return nil
+		}
+		if !coerce {
+			return errors.New("the latency multiplier must be > 0")
+		}
+		// keep the default value
+		return nil
+	}
+}
+
+// latencySmoothing is the weight of each new sample in the moving average of
+// observed latency.
+const latencySmoothing = 0.2
+
+// ObserveLatency feeds a sample of the downstream's latency into an
+// exponentially weighted moving average, and sets the base delay to the
+// average times the latency multiplier (see WithLatencyMultiplier), so that
+// backoff scales with the actual performance of the downstream. Each sample
+// moves the average 20% of the way towards it, so the average responds to a
+// step change in latency with a time constant of about 5 samples. The first
+// sample seeds the average directly. Negative samples are ignored.
+//
+// The base delay is the starting point of growth when the initial delay is 0,
+// or when WithBaseDelayAlways is used.
+func (b *Backoff) ObserveLatency(d time.Duration) {
+	if d < 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.latencyAvg == 0 {
+		b.latencyAvg = float64(d)
+	} else {
+		b.latencyAvg += latencySmoothing * (float64(d) - b.latencyAvg)
+	}
+	b.baseDelay = max(time.Duration(math.Round(b.latencyAvg*b.latencyMultiplier)), 1)
+}
+
+// WithPlateauPause configuration BackoffOption inserts a long pause into the
+// plateau, so that a dead dependency is periodically given a real break
+// rather than being retried at the plateau interval forever. After every
+// afterRounds consecutive rounds at the exponential limit, the next round uses
+// the pause delay (before jitter) instead, and then the plateau resumes. The
+// rounds must be > 0, and the pause must be >= 0. By default there is no
+// plateau pause.
+func WithPlateauPause(afterRounds int, pause time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if afterRounds > 0 && pause >= 0 {
+			b.pauseAfter = afterRounds
+			b.pauseDelay = pause
+			return nil
+		}
+		if !coerce {
+			return errors.New("the plateau pause rounds must be > 0, and the pause must be >= 0")
+		}
+		// assume caller wanted no plateau pause
+		b.pauseAfter = 0
+		b.pauseDelay = 0
+		return nil
+	}
+}
+
+// WithGlobalSlots configuration BackoffOption aligns every delay to global
+// slots, so that all clients retry in a synchronized-but-offset, and fully
+// deterministic, pattern that makes the downstream load predictable. Time is
+// divided into slots of the given length, counting from the Unix epoch, and
+// each delay lasts until the start of the next slot plus the client's fixed
+// offset. This replaces growth and jitter entirely, although the backoff still
+// counts attempts. The slot must be > 0, and the offset must be >= 0. By
+// default delays are not aligned to global slots.
+func WithGlobalSlots(slot time.Duration, clientOffset time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if slot > 0 && clientOffset >= 0 {
+			b.slot = slot
+			b.slotOffset = clientOffset
+			return nil
+		}
+		if !coerce {
+			return errors.New("the global slot must be > 0, and the client offset must be >= 0")
+		}
+		// assume caller wanted no global slots
+		b.slot = 0
+		b.slotOffset = 0
+		return nil
+	}
+}
+
+// WithDelayOverride configuration BackoffOption sets a callback that can
+// replace the delay of each round. It is called with the attempt number
+// (counting from 1) and the planned delay, after growth (and any constant
+// fallback) but before jitter, so jitter is applied to the delay it returns.
+// Returning the planned delay leaves it unchanged, and negative delays are
+// treated as 0. The override does not affect the growth of the backoff, nor
+// what PeekDelay reports. The callback must not call methods of the backoff,
+// except Metadata.
+func WithDelayOverride(fn func(attempt int, planned time.Duration) time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		b.override = fn
+		return nil
+	}
+}
+
+// ExhaustedPolicy identifies what Sleep does once the max attempts, or the max
+// elapsed time, have been exhausted.
+type ExhaustedPolicy int
+
+const (
+	// ExhaustedNoop returns immediately, without sleeping.
+	ExhaustedNoop ExhaustedPolicy = iota
+	// ExhaustedSleepPlateau sleeps the plateau delay, without advancing.
+	ExhaustedSleepPlateau
+	// ExhaustedPanic panics, e.g. to fail fast in tests.
+	ExhaustedPanic
+)
+
+var exhaustedPolicyNames = map[ExhaustedPolicy]string{
+	ExhaustedNoop:         "noop",
+	ExhaustedSleepPlateau: "plateau",
+	ExhaustedPanic:        "panic",
+}
+
+// String returns the name of the exhausted policy, as accepted by
+// ParseExhaustedPolicy.
+func (p ExhaustedPolicy) String() string {
+	if name, ok := exhaustedPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("ExhaustedPolicy(%d)", int(p))
+}
+
+// ParseExhaustedPolicy returns the exhausted policy with the given name,
+// ignoring case and surrounding whitespace, e.g. for selecting the policy from
+// a configuration file.
+func ParseExhaustedPolicy(name string) (ExhaustedPolicy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for p, n := range exhaustedPolicyNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown exhausted policy: %q", name)
+}
+
+// WithMaxElapsed configuration BackoffOption limits the total time over which
+// the backoff can be performed. The clock starts at the first round (i.e. the
+// first sleep, or call to Next), not at construction, and once the limit has
+// elapsed, Done reports true, as with WithMaxAttempts. The limit must be >= 0,
+// and the default is 0, meaning that there is no limit.
+func WithMaxElapsed(d time.Duration) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if d >= 0 {
+			b.maxElapsed = d
+			return nil
+		}
+		if !coerce {
+			return errors.New("the max elapsed time must be >= 0")
+		}
+		// assume caller wanted no limit
+		b.maxElapsed = 0
+		return nil
+	}
+}
+
+// WithName configuration BackoffOption names the backoff, so that several
+// backoffs, e.g. one per downstream service, can be told apart in logs. The
+// name is reported by Name and String, and passed to the callback set by
+// WithOnRetry. Clones share the name. The default is no name.
+func WithName(name string) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		b.name = name
+		return nil
+	}
+}
+
+// WithOnRetry configuration BackoffOption sets a callback that observes each
+// round of the backoff, e.g. for logging or metrics. It is called whenever the
+// backoff advances (by Sleep, Next, and so on), before any sleep, with the
+// name of the backoff (see WithName), the attempt number (counting from 1) and
+// the delay about to be used, after jitter. It is called without the lock
+// held, so it may call methods of the backoff, e.g. Metadata, although it must
+// not advance it.
+func WithOnRetry(fn func(name string, attempt int, delay time.Duration)) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		b.onRetry = fn
+		return nil
+	}
+}
+
+// WithOnExhausted configuration BackoffOption sets what Sleep does once the
+// max attempts, or the max elapsed time, have been exhausted. Done reports the
+// exhaustion regardless of the policy. The default is ExhaustedNoop.
+func WithOnExhausted(policy ExhaustedPolicy) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if policy >= ExhaustedNoop && policy <= ExhaustedPanic {
+			b.onExhausted = policy
+			return nil
+		}
+		if !coerce {
+			return errors.New("unknown exhausted policy")
+		}
+		// assume caller wanted the safe default
+		b.onExhausted = ExhaustedNoop
+		return nil
+	}
+}
+
+// Next advances the backoff without sleeping, and returns the jittered delay
+// for this round, for callers that schedule retries themselves, e.g. from an
+// event loop. It is the delay that Sleep would have slept for. Unlike
+// NextDelay, it advances even if the max attempts have been exhausted.
+func (b *Backoff) Next() time.Duration {
+	b.mu.Lock()
+	d := b.computeDelay()
+	notify := b.retryHook(d)
+	b.mu.Unlock()
+
+	notify()
+	return d
+}
+
+// NextAt advances the backoff like Next, but returns the delay for this round
+// less the time already elapsed since last, as measured by the backoff's
+// clock, and never less than 0 (so the delay is extended if last is in the
+// future). It keeps a polling loop on schedule, without cumulative drift,
+// however long its handler takes, when last is the time that the loop intended
+// to wake at:
+//
+//	wake := time.Now()
+//	for {
+//		poll()
+//		d := b.NextAt(wake)
+//		wake = time.Now().Add(d)
+//		time.Sleep(d)
+//	}
+//
+// For near-fixed intervals, use a backoff that does not grow, e.g. with an
+// exponential limit of 0, and a small jitter factor.
+func (b *Backoff) NextAt(last time.Time) time.Duration {
+	b.mu.Lock()
+	d := b.computeDelay()
+	elapsed := b.clock.Now().Sub(last)
+	notify := b.retryHook(d)
+	b.mu.Unlock()
+
+	notify()
+	return max(d-elapsed, 0)
+}
+
+// Sleep pauses execution on the current thread, for the delay that Next
+// returns, advancing the backoff in the same way. The duration of the sleep
 // increases exponentially, up to a limit, and random jitter is applied to
-// mitigate the thundering herd problem.
+// mitigate the thundering herd problem. If the max attempts have been
+// exhausted, it returns immediately, by default (see WithOnExhausted).
 func (b *Backoff) Sleep() {
-	time.Sleep(b.computeDelay())
+	b.mu.Lock()
+	if b.done() {
+		switch b.onExhausted {
+		case ExhaustedSleepPlateau:
+			d, clock := b.plateauDelay(), b.clock
+			b.mu.Unlock()
+			sleep(clock, d)
+			return
+		case ExhaustedPanic:
+			b.mu.Unlock()
+			panic("backoff: Sleep called after the limits were exhausted")
+		default:
+			b.mu.Unlock()
+			return
+		}
+	}
+	d := b.computeDelay()
+	notify, clock := b.retryHook(d), b.clock
+	b.mu.Unlock()
+
+	notify()
+	sleep(clock, d)
+}
+
+// After advances the backoff, like Next, and returns a channel that receives
+// the current time once the delay has elapsed, like time.After, for use in a
+// select statement. Each call advances the sequence, whether or not the
+// channel is ever read. No goroutine is started, so it is fine to drop the
+// channel, although the underlying timer is only released once it fires.
+func (b *Backoff) After() <-chan time.Time {
+	clock := b.currentClock()
+	return clock.NewTimer(b.Next()).C()
+}
+
+// Wait advances the backoff, like Next, and returns a channel that is closed
+// once the delay has elapsed, or once the context is done, whichever is first,
+// for use in a select statement alongside other events. The caller can check
+// the context's error to tell which happened. Each call advances the sequence,
+// even if the context is already done. The timer is stopped as soon as the
+// context is done, so nothing is left running once the channel is closed.
+func (b *Backoff) Wait(ctx context.Context) <-chan struct{} {
+	clock := b.currentClock()
+	d := b.Next()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = sleepContext(ctx, clock, d)
+	}()
+	return done
+}
+
+// WithClock configuration BackoffOption sets the source of time for the
+// backoff, e.g. a fake clock, to test timing behavior (sleeps, the max elapsed
+// time, global slots) quickly and deterministically. The default is the real
+// time of the time package.
+func WithClock(c Clock) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if c != nil {
+			b.clock = c
+			return nil
+		}
+		if !coerce {
+			return errors.New("the clock must not be nil")
+		}
+		// keep default value
+		return nil
+	}
+}
+
+// currentClock returns the clock of the backoff.
+func (b *Backoff) currentClock() Clock {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.clock
+}
+
+// ErrBudgetExhausted is returned by TrySleep once the max attempts, or the max
+// elapsed time, have been exhausted.
+var ErrBudgetExhausted = errors.New("backoff: budget exhausted")
+
+// TrySleep pauses execution like Sleep, but once the max attempts, or the max
+// elapsed time, have been exhausted, it returns ErrBudgetExhausted instead of
+// sleeping (regardless of WithOnExhausted), so that a retry loop can tell
+// giving up apart from retrying without a separate call to Done.
+func (b *Backoff) TrySleep() error {
+	b.mu.Lock()
+	if b.done() {
+		b.mu.Unlock()
+		return ErrBudgetExhausted
+	}
+	d := b.computeDelay()
+	notify, clock := b.retryHook(d), b.clock
+	b.mu.Unlock()
+
+	notify()
+	sleep(clock, d)
+	return nil
+}
+
+// WithAdvanceOnCancel configuration BackoffOption controls whether SleepContext
+// advances the backoff when the context is already done on entry. By default
+// it does not, so a cancelled sleep consumes neither an attempt nor a round of
+// growth. Either way, SleepContext returns the context's error without
+// sleeping.
+func WithAdvanceOnCancel(advance bool) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		b.advanceOnCancel = advance
+		return nil
+	}
+}
+
+// SleepContext pauses execution like Sleep, but returns early with the
+// context's error if the context is done before the delay elapses. The backoff
+// advances whether or not the sleep completes, except that, if the context is
+// already done on entry, it returns immediately without advancing (unless
+// WithAdvanceOnCancel is used). If the max attempts have been exhausted, it
+// returns nil immediately.
+func (b *Backoff) SleepContext(ctx context.Context) error {
+	d, clock, err := b.advanceContext(ctx)
+	if err != nil || d == Stop {
+		return err
+	}
+	return sleepContext(ctx, clock, d)
+}
+
+// advanceContext advances the backoff for SleepContext, and returns the delay
+// to sleep for, measured by the returned clock. It returns the context's error
+// if the context is already done, or Stop if the limits are exhausted.
+func (b *Backoff) advanceContext(ctx context.Context) (time.Duration, Clock, error) {
+	b.mu.Lock()
+	if err := ctx.Err(); err != nil {
+		notify := func() {}
+		if b.advanceOnCancel && !b.done() {
+			notify = b.retryHook(b.computeDelay())
+		}
+		b.mu.Unlock()
+		notify()
+		return 0, nil, err
+	}
+	if b.done() {
+		b.mu.Unlock()
+		return Stop, nil, nil
+	}
+	d := b.computeDelay()
+	notify, clock := b.retryHook(d), b.clock
+	b.mu.Unlock()
+
+	notify()
+	return d, clock, nil
+}
+
+// Advance advances the backoff without sleeping, and returns the jittered
+// delay for this round together with the number of attempts made so far
+// (including this one), as a single atomic step. Under concurrent use this
+// avoids the race of reading the attempt count separately, which could observe
+// another caller's advance. It advances even if the max attempts have been
+// exhausted.
+func (b *Backoff) Advance() (delay time.Duration, attempt int) {
+	b.mu.Lock()
+	delay, attempt = b.computeDelay(), b.attempts
+	notify := b.retryHook(delay)
+	b.mu.Unlock()
+
+	notify()
+	return delay, attempt
+}
+
+// Stop is the sentinel that NextDelay returns once the limits are exhausted.
+// It is negative, so it can never be mistaken for a real delay.
+const Stop time.Duration = -1
+
+// NextDelay advances the backoff without sleeping, and returns the jittered
+// delay for this round, for callers that manage their own sleeping. Once the
+// max attempts, or the max elapsed time, have been exhausted, it returns Stop
+// instead, without advancing:
+//
+//	for d := b.NextDelay(); d != backoff.Stop; d = b.NextDelay() {
+//		...
+//	}
+func (b *Backoff) NextDelay() time.Duration {
+	b.mu.Lock()
+	if b.done() {
+		b.mu.Unlock()
+		return Stop
+	}
+	d := b.computeDelay()
+	notify := b.retryHook(d)
+	b.mu.Unlock()
+
+	notify()
+	return d
+}
+
+// Attempts returns the number of times the backoff has advanced (i.e. the
+// number of sleeps, or calls to Next), since it was created or last Reset,
+// whether or not a max attempts limit is configured.
+func (b *Backoff) Attempts() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempts
+}
+
+// Stats summarizes the rounds of a backoff since it was created or last Reset.
+type Stats struct {
+	Attempts   int           // the number of rounds, as reported by Attempts
+	TotalDelay time.Duration // the sum of their delays, after jitter
+	LastDelay  time.Duration // the delay of the most recent round
+}
+
+// Stats returns the cumulative timing of the backoff, e.g. for dashboards,
+// without wiring up a callback. Every round counts, however the backoff was
+// advanced (by Sleep, Next, and so on), so the total delay is the time slept
+// by Sleep, or the time the caller was told to wait by Next. Reset zeroes the
+// stats.
+func (b *Backoff) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{Attempts: b.attempts, TotalDelay: b.delaySum, LastDelay: b.finalDelay}
+}
+
+// Done reports whether the max attempts, or the max elapsed time, have been
+// exhausted. It is always false if no such limit was configured.
+func (b *Backoff) Done() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done()
+}
+
+func (b *Backoff) done() bool {
+	if b.maxAttempts > 0 && b.attempts >= b.maxAttempts {
+		return true
+	}
+	return b.maxElapsed > 0 && !b.start.IsZero() && b.clock.Now().Sub(b.start) >= b.maxElapsed
 }
 
 // PeekDelay allows the caller to query the hext delay without performing the
 // backoff (i.e. without pausing execution or growing the backoff delay).
 func (b *Backoff) PeekDelay() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	return b.delay
 }
 
+// PeekRange allows the caller to query the range within which the next delay
+// will fall once jitter is applied, without performing the backoff.
+func (b *Backoff) PeekRange() (min, max time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.peekRange()
+}
+
+// PeekJittered returns a representative delay for the next round, picked
+// uniformly at random from the range reported by PeekRange, without advancing
+// the backoff, e.g. for "next retry in ~3s" text. It is nondeterministic: it
+// draws from the global source of randomness, rather than the backoff's own,
+// so that it does not disturb the delays of a backoff with a seeded source,
+// and it generally differs from the delay the next round actually uses.
+func (b *Backoff) PeekJittered() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lo, hi := b.peekRange()
+	return lo + time.Duration(math.Round(rand.Float64()*float64(hi-lo)))
+}
+
+// peekRange returns the range within which the next delay will fall.
+func (b *Backoff) peekRange() (min, max time.Duration) {
+	lo, hi := b.jitterRange(b.delay, b.limitAsFloor && b.atLimit())
+	if b.jitterMode == JitterDecorrelated {
+		lo, hi = b.decorrelatedRange()
+	}
+	lo, hi = b.clampRange(lo, hi)
+	return time.Duration(math.Round(lo)), time.Duration(math.Round(hi))
+}
+
+// clampRange clamps a range of delays the way computeDelay clamps the delay:
+// to at least the min delay (and 0), and to at most any max delay.
+func (b *Backoff) clampRange(lo, hi float64) (float64, float64) {
+	floor := float64(max(b.minDelay, 0))
+	lo, hi = math.Max(lo, floor), math.Max(hi, floor)
+	if b.maxDelay > 0 {
+		lo, hi = math.Min(lo, float64(b.maxDelay)), math.Min(hi, float64(b.maxDelay))
+	}
+	return lo, hi
+}
+
+// Name returns the name of the backoff set by WithName, if any.
+func (b *Backoff) Name() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.name
+}
+
+// String describes the configuration of the backoff, for logging, e.g.
+// "Backoff{initial=100ms, base=100ms, expLimit=3m0s, jitter=0.30}". Any name
+// comes first, e.g. "Backoff{name="db", initial=100ms, ...}", and settings
+// that differ from their defaults, like the multiplier, are appended. It does
+// not reflect the current progress of the backoff.
+func (b *Backoff) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString("Backoff{")
+	if b.name != "" {
+		fmt.Fprintf(&sb, "name=%q, ", b.name)
+	}
+	fmt.Fprintf(&sb, "initial=%v, base=%v, expLimit=%v, jitter=%.2f",
+		b.initDelay, b.baseDelay, b.expLimit, b.jitterFactor)
+	if b.multiplier != defaultMultiplier {
+		fmt.Fprintf(&sb, ", multiplier=%g", b.multiplier)
+	}
+	if b.growth != GrowthExponential {
+		fmt.Fprintf(&sb, ", growth=%v", b.growth)
+	}
+	if b.maxDoublings > 0 {
+		fmt.Fprintf(&sb, ", maxDoublings=%d", b.maxDoublings)
+	}
+	if b.softLimit {
+		sb.WriteString(", softLimit")
+	}
+	if b.jitterMode != JitterSymmetric {
+		fmt.Fprintf(&sb, ", mode=%v", b.jitterMode)
+	}
+	if b.absJitter > 0 {
+		fmt.Fprintf(&sb, ", absJitter=%v", b.absJitter)
+	}
+	if b.jitterBounds {
+		fmt.Fprintf(&sb, ", jitterBounds=[%g, %g]", b.jitterLow, b.jitterHigh)
+	}
+	if b.maxJitter > 0 {
+		fmt.Fprintf(&sb, ", maxJitter=%v", b.maxJitter)
+	}
+	if b.maxDelay > 0 {
+		fmt.Fprintf(&sb, ", maxDelay=%v", b.maxDelay)
+	}
+	if b.maxAttempts > 0 {
+		fmt.Fprintf(&sb, ", maxAttempts=%d", b.maxAttempts)
+	}
+	if b.maxElapsed > 0 {
+		fmt.Fprintf(&sb, ", maxElapsed=%v", b.maxElapsed)
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// retryHook returns a call of the OnRetry callback, if any, for the round that
+// was just computed with delay d. The caller must hold the lock, but release it
+// before making the call, so that the callback can use the backoff.
+func (b *Backoff) retryHook(d time.Duration) func() {
+	if b.onRetry == nil {
+		return func() {}
+	}
+	fn, name, attempt := b.onRetry, b.name, b.attempts
+	return func() { fn(name, attempt, d) }
+}
+
+// computeDelay advances the backoff, and returns the jittered delay for this
+// round. The caller must hold the lock.
 func (b *Backoff) computeDelay() time.Duration {
-	// compute current backoff by adding jitter
-	j := 1.0 + (rand.Float64()-0.5)*b.jitterFactor
-	d := float64(b.delay.Nanoseconds()) * j
+	scripted := b.scriptNext < len(b.script)
+	var delay time.Duration
+	if scripted {
+		// scripted delays are used exactly, without growing the backoff
+		delay = b.script[b.scriptNext]
+		b.scriptNext++
+		b.spreadDone = true
+	} else {
+		delay = b.unscriptedDelay()
+	}
+	if b.start.IsZero() {
+		b.start = b.clock.Now()
+	}
+	if b.history != nil {
+		b.history.add(Event{Time: b.clock.Now(), Delay: delay, AtLimit: b.atLimit()})
+	}
 
 	// update state for the next backoff round
-	if b.delay == 0.0 {
-		b.delay = b.baseDelay
-	} else if b.delay < b.expLimit {
-		b.delay *= 2.0
+	if !scripted {
+		b.progress = b.grow(b.progress)
+	}
+	b.attempts++
+	b.delaySum = saturatingAdd(b.delaySum, delay)
+	b.finalDelay = delay
+
+	if b.recorder != nil {
+		b.recorder.record(delay)
+	}
+	return delay
+}
+
+// unscriptedDelay returns the delay for the current round, from the growth of
+// the backoff, or any override, with jitter and any startup spread applied,
+// within the min and max delays.
+func (b *Backoff) unscriptedDelay() time.Duration {
+	var delay time.Duration
+	switch {
+	case b.forced:
+		delay = b.forcedJitteredDelay()
+	case b.slot > 0:
+		delay = b.untilSlot()
+	default:
+		delay = b.jitteredDelay()
+	}
+	if !b.spreadDone {
+		b.spreadDone = true
+		if b.startupSpread > 0 {
+			delay = saturatingAdd(delay, time.Duration(b.rng.Int63n(int64(b.startupSpread)+1)))
+		}
+	}
+	// never negative, whatever the jitter, even without a min delay
+	delay = max(delay, b.minDelay, 0)
+	if b.maxDelay > 0 {
+		delay = min(delay, b.maxDelay)
+	}
+	return delay
+}
+
+// jitteredDelay returns the delay for the current round, with jitter applied.
+func (b *Backoff) jitteredDelay() time.Duration {
+	delay, upward := b.delay, b.limitAsFloor && b.atLimit()
+	decorrelated := false
+	switch {
+	case b.plateauPause():
+		delay, upward = b.pauseDelay, false
+	case b.fallbackProb > 0 && b.rng.Float64() < b.fallbackProb:
+		// randomly fall back to the constant delay, without touching growth state
+		delay, upward = b.fallbackDelay, false
+	case b.jitterMode == JitterDecorrelated:
+		// the delay is already random, so no further jitter is applied
+		lo, hi := b.decorrelatedRange()
+		delay, decorrelated = time.Duration(math.Round(lo+b.rng.Float64()*(hi-lo))), true
+	}
+
+	if b.override != nil {
+		delay = max(b.override(b.attempts+1, delay), 0)
+	}
+	if decorrelated {
+		b.lastDelay = delay
+		return delay
 	}
 
+	// compute current backoff by adding jitter
+	lo, hi := b.jitterRange(delay, upward)
+	d := lo + b.rng.Float64()*(hi-lo)
+
 	return time.Duration(int(math.Round(d)))
 }
+
+// forcedJitteredDelay returns the delay set by Override, with jitter that only
+// increases it, and clears it.
+func (b *Backoff) forcedJitteredDelay() time.Duration {
+	b.forced = false
+	lo, hi := b.jitterRange(b.forcedDelay, true)
+	delay := time.Duration(math.Round(lo + b.rng.Float64()*(hi-lo)))
+	if b.jitterMode == JitterDecorrelated {
+		b.lastDelay = delay
+	}
+	return delay
+}
+
+// decorrelatedRange returns the range within which decorrelated jitter picks
+// the delay for the current round.
+func (b *Backoff) decorrelatedRange() (lo, hi float64) {
+	prev := max(b.lastDelay, b.baseDelay)
+	limit := float64(b.expLimit)
+	return math.Min(float64(b.baseDelay), limit), math.Min(float64(prev)*3, limit)
+}
+
+// untilSlot returns the time until the client's offset into the next global
+// slot.
+func (b *Backoff) untilSlot() time.Duration {
+	now := b.clock.Now().UnixNano()
+	next := (now/int64(b.slot) + 1) * int64(b.slot)
+	return time.Duration(next-now) + b.slotOffset
+}
+
+// plateauPause reports whether this round is a long pause, counting the
+// consecutive rounds at the plateau.
+func (b *Backoff) plateauPause() bool {
+	if b.pauseAfter == 0 || !b.atLimit() {
+		b.plateauRounds = 0
+		return false
+	}
+	if b.plateauRounds == b.pauseAfter {
+		b.plateauRounds = 0
+		return true
+	}
+	b.plateauRounds++
+	return false
+}
+
+// jitterRange returns the range within which jitter moves the delay d. Jitter
+// is normally centered on d, though never below 0 (or spans [0, d] in full
+// jitter mode, or [d/2, d] in equal jitter mode, or within the jitter bounds),
+// but when upward is set it only ever increases d. Its spread is proportional
+// to d, unless absolute jitter is set, and it moves d by at most the max
+// absolute jitter, if set.
+func (b *Backoff) jitterRange(d time.Duration, upward bool) (lo, hi float64) {
+	spread := float64(d.Nanoseconds()) * b.jitterFactor
+	if b.absJitter > 0 {
+		spread = 2 * float64(b.absJitter)
+	}
+	if b.jitterBounds {
+		spread = float64(d) * (b.jitterHigh - b.jitterLow)
+	}
+	switch {
+	case upward:
+		lo, hi = float64(d), float64(d)+spread
+	case b.jitterMode == JitterFull:
+		lo, hi = 0, float64(d)
+	case b.jitterMode == JitterEqual:
+		lo, hi = float64(d)/2, float64(d)
+	case b.jitterBounds:
+		lo, hi = float64(d)*(1+b.jitterLow), float64(d)*(1+b.jitterHigh)
+	default:
+		lo, hi = math.Max(float64(d)-spread/2, 0), float64(d)+spread/2
+	}
+	if b.maxJitter > 0 {
+		lo = math.Max(lo, float64(d)-float64(b.maxJitter))
+		hi = math.Min(hi, float64(d)+float64(b.maxJitter))
+	}
+	return lo, hi
+}
+
+// clone returns a copy of the backoff's configuration, in its initial state.
+func (b *Backoff) clone() *Backoff {
+	c := &Backoff{settings: b.settingsCopy()}
+	c.reset()
+	if b.history != nil {
+		c.history = newHistory(len(b.history.buf))
+	}
+	return c
+}
+
+// settingsCopy returns a copy of the backoff's configuration, with its own
+// source of randomness, seeded from the backoff's, so that copies can be used
+// concurrently, yet remain deterministic given a seeded source.
+func (b *Backoff) settingsCopy() settings {
+	s := b.settings
+	s.rng = rand.New(rand.NewSource(b.rng.Int63()))
+	return s
+}
+
+// Clone returns a new backoff with the same configuration, in its initial
+// state: the next delay is the initial delay, no attempts have been made, and
+// any history is empty. It can be used to create independent per-request
+// backoffs from a template configured once. Metadata is not copied.
+func (b *Backoff) Clone() *Backoff {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.clone()
+}
+
+// Reset restores the backoff to its initial state, e.g. to reuse it for the
+// next independent operation once a retry loop has succeeded. The next delay
+// is the configured initial delay (see SetInitialDelay), and the attempt count,
+// the stats, and the start time for the max elapsed time are cleared, so Done
+// reports false. Recorded delays, history and metadata are kept, as is the
+// moving average of observed latency.
+func (b *Backoff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+}
+
+// ResetTo restores the backoff like Reset, but resumes it at d instead of the
+// initial delay, e.g. to warm-start it at a delay known from persisted state.
+// The delay is clamped to the range [base delay, exponential limit], or to the
+// base delay if the exponential limit is below it. Any scheduled delays are
+// skipped, and the max doublings are counted from d. On a replayed backoff,
+// it is the same as Reset.
+func (b *Backoff) ResetTo(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+	if len(b.replay) > 0 {
+		return
+	}
+	d = min(max(d, b.baseDelay), max(b.expLimit, b.baseDelay))
+	// resume as if d had grown from the base delay, so that the rounds
+	// after it follow the normal schedule
+	b.progress = progress{
+		delay:  d,
+		prev:   time.Duration(float64(d) / math.Phi),
+		rounds: 1,
+	}
+	b.scriptNext = len(b.script)
+}
+
+// reset restores the backoff to its initial state.
+func (b *Backoff) reset() {
+	b.progress = progress{delay: b.initDelay}
+	b.attempts = 0
+	b.plateauRounds = 0
+	b.lastDelay = 0
+	b.forced = false
+	b.delaySum = 0
+	b.finalDelay = 0
+	b.scriptNext = 0
+	b.start = time.Time{}
+}
+
+// InitialDelay returns the configured initial delay (before jitter), i.e. the
+// first delay of the backoff, which is unaffected by advancing it.
+func (b *Backoff) InitialDelay() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.initDelay
+}
+
+// SetInitialDelay changes the initial delay of the backoff at runtime, e.g. to
+// tighten it after a cold start. Like WithInitialDelay in CoerceNew, a
+// negative delay is coerced to 0. The new initial delay is used whenever the
+// backoff is restored to its initial state, and also immediately if the
+// backoff has not yet advanced.
+func (b *Backoff) SetInitialDelay(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d = max(d, 0)
+	// scripted rounds advance the backoff without changing its progress
+	if b.attempts == 0 && b.scriptNext == 0 {
+		b.delay = d
+	}
+	b.initDelay = d
+}
+
+// Override forces the next round to use d as its delay, before jitter, instead
+// of the planned delay, e.g. to honor a Retry-After hint from a server. Jitter
+// only increases d, so the delay is never shorter than d, and is exactly d
+// with a jitter factor of 0. Otherwise the round is like any other: it counts
+// as an attempt, and the backoff grows as planned, so later rounds are
+// unaffected. The delay is still clamped by any min and max delays, so a
+// longer hint is cut short by WithMaxDelay. A negative d is treated as 0.
+// Calling Override again before the next round replaces d, and Reset clears
+// it. It does not affect what PeekDelay and PeekRange report.
+func (b *Backoff) Override(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.forced, b.forcedDelay = true, max(d, 0)
+}
+
+// Decrease shrinks the delay of the backoff by one growth step, without
+// jitter, e.g. halving it with the default multiplier, down to a floor of the
+// base delay. Combined with the growth on each failed attempt, calling it after
+// each success gives AIMD-style control, e.g. in an adaptive concurrency
+// limiter. It does not change the attempt count, nor the start time for the
+// max elapsed time, so it does not undo the exhaustion reported by Done,
+// whereas Reset clears those and restores the initial delay. It has no effect
+// once the delay is at or below the base delay, or on a replayed backoff.
+func (b *Backoff) Decrease() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.replay) > 0 || b.delay <= b.baseDelay {
+		return
+	}
+	p := b.progress
+	switch b.growth {
+	case GrowthFibonacci:
+		p.delay, p.prev = p.prev, p.delay-p.prev
+	case GrowthLinear:
+		p.delay -= b.baseDelay
+	default:
+		p.delay = time.Duration(float64(p.delay) / b.multiplier)
+	}
+	p.delay = max(p.delay, b.baseDelay)
+	p.grown = max(p.grown-1, 0)
+	b.progress = p
+}
+
+// GrowthStalled reports whether the backoff has stopped growing before reaching
+// the exponential limit (or the max doublings), because multiplying the
+// current delay does not change it (e.g. a 1ns delay with a multiplier close
+// to 1). This is a diagnostic for a misconfigured schedule that is not
+// actually growing.
+func (b *Backoff) GrowthStalled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.atLimit() && len(b.replay) == 0 && !b.pastLimit(b.progress)
+}
+
+// pastLimit reports whether the backoff has reached the exponential limit, or
+// made the max doublings, at progress p.
+func (b *Backoff) pastLimit(p progress) bool {
+	return p.delay >= b.expLimit || b.doubledOut(p)
+}
+
+// doubledOut reports whether the backoff has made the max doublings at
+// progress p.
+func (b *Backoff) doubledOut(p progress) bool {
+	return b.maxDoublings > 0 && p.grown >= b.maxDoublings
+}
+
+// Scaled returns a new backoff, in its initial state, whose delays are those of
+// b multiplied by factor. Every delay-valued setting is scaled: the initial
+// delay, base delay and exponential limit, and any min and max delays,
+// absolute and max jitter, startup spread, constant fallback, plateau pause,
+// global slots, and scripted or replayed delays, while the jitter factor,
+// growth settings and limits on attempts and elapsed time are preserved. This
+// derives a gentler (or harsher) backoff for nested retries, e.g. a 0.1x child
+// of a parent with a 1s base delay has a base delay of 100ms. The factor must
+// be > 0, otherwise it is coerced to 1.
+func (b *Backoff) Scaled(factor float64) *Backoff {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !(factor > 0) {
+		factor = 1
+	}
+	scale := func(d time.Duration) time.Duration {
+		return time.Duration(math.Round(float64(d) * factor))
+	}
+
+	c := b.clone()
+	c.initDelay = scale(c.initDelay)
+	c.baseDelay = max(scale(c.baseDelay), 1)
+	c.expLimit = scale(c.expLimit)
+	if c.maxDelay > 0 {
+		c.maxDelay = max(scale(c.maxDelay), 1)
+	}
+	c.fallbackDelay = scale(c.fallbackDelay)
+	c.absJitter = scale(c.absJitter)
+	c.maxJitter = scale(c.maxJitter)
+	c.minDelay = scale(c.minDelay)
+	c.startupSpread = scale(c.startupSpread)
+	c.pauseDelay = scale(c.pauseDelay)
+	if c.slot > 0 {
+		c.slot = max(scale(c.slot), 1)
+		c.slotOffset = scale(c.slotOffset)
+	}
+	if c.replay != nil {
+		c.replay = make([]time.Duration, len(b.replay))
+		for i, d := range b.replay {
+			c.replay[i] = scale(d)
+		}
+	}
+	if c.script != nil {
+		c.script = make([]time.Duration, len(b.script))
+		for i, d := range b.script {
+			c.script[i] = scale(d)
+		}
+	}
+	c.progress = progress{delay: c.initDelay}
+	return c
+}
+
+// atLimit reports whether the backoff has stopped growing.
+func (b *Backoff) atLimit() bool {
+	return b.plateaued(b.progress)
+}
+
+// grow returns the progress of the backoff after the round described by p.
+func (b *Backoff) grow(p progress) progress {
+	if n := len(b.replay); n > 0 {
+		p.rounds++
+		p.delay = b.replay[min(p.rounds, n-1)]
+		return p
+	}
+
+	switch {
+	case p.delay == 0, b.baseAlways && p.rounds == 0:
+		p.delay, p.prev = b.baseDelay, 0
+	case b.plateaued(p):
+	case b.softLimit && b.pastLimit(p):
+		// creep linearly beyond the soft limit
+		p.delay = min(saturatingAdd(p.delay, b.baseDelay), b.maxDelay)
+	default:
+		switch b.growth {
+		case GrowthFibonacci:
+			p.delay, p.prev = saturatingAdd(p.delay, p.prev), p.delay
+		case GrowthLinear:
+			p.delay = saturatingAdd(p.delay, b.baseDelay)
+		default:
+			p.delay = b.multiply(p.delay)
+		}
+		if b.maxDelay > 0 {
+			p.delay = min(p.delay, b.maxDelay)
+		}
+		p.grown++
+	}
+	p.rounds++
+	return p
+}
+
+// plateaued reports whether the backoff has stopped growing at progress p,
+// either because it reached the limit (or the max doublings), or because
+// growth has stalled.
+func (b *Backoff) plateaued(p progress) bool {
+	if n := len(b.replay); n > 0 {
+		return p.rounds >= n-1
+	}
+	if p.delay == 0 || b.baseAlways && p.rounds == 0 {
+		return false
+	}
+	if b.pastLimit(p) {
+		// a soft limit only stops growth at the max delay
+		return !b.softLimit || p.delay >= b.maxDelay
+	}
+	if b.growth == GrowthExponential {
+		return b.multiply(p.delay) == p.delay
+	}
+	// additive growth only stalls once it saturates
+	return p.delay == math.MaxInt64
+}
+
+// saturatingAdd returns a+b, saturating rather than overflowing.
+func saturatingAdd(a, b time.Duration) time.Duration {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// multiply returns d grown by the multiplier, saturating rather than
+// overflowing.
+func (b *Backoff) multiply(d time.Duration) time.Duration {
+	next := float64(d) * b.multiplier
+	if next >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(next)
+}
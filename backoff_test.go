@@ -1,28 +1,44 @@
 package backoff
 
 import (
+	"context"
+	"errors"
 	"math"
+	"math/rand"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
 
+// params holds the configurable inputs of a Backoff, for table-driven tests.
+type params struct {
+	delay        time.Duration
+	baseDelay    time.Duration
+	expLimit     time.Duration
+	jitterFactor float64
+	multiplier   float64
+}
+
 func TestNewConstructor(t *testing.T) {
 	tests := map[string]struct {
-		inputs    Backoff
+		inputs    params
 		expectErr bool
 	}{
-		"ok with default inputs":            {Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor}, false},
-		"ok with 0 init delay":              {Backoff{0, defaultBaseDelay, defaultExpLimit, defaultJitterFactor}, false},
-		"ok with 0 exp limit":               {Backoff{defaultInitDelay, defaultBaseDelay, 0, defaultJitterFactor}, false},
-		"ok with 0 jitter factor":           {Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 0}, false},
-		"fails with negative init delay":    {Backoff{-1, defaultBaseDelay, defaultExpLimit, defaultJitterFactor}, true},
-		"fails with negative base delay":    {Backoff{defaultInitDelay, -1, defaultExpLimit, defaultJitterFactor}, true},
-		"fails with 0 base delay":           {Backoff{defaultInitDelay, 0, defaultExpLimit, defaultJitterFactor}, true},
-		"fails with negative exp limit":     {Backoff{defaultInitDelay, defaultBaseDelay, -1, defaultJitterFactor}, true},
-		"fails with negative jitter factor": {Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, -1}, true},
-		"fails with jitter factor == 1":     {Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 1}, true},
-		"fails with jitter factor > 1":      {Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 1.3}, true},
+		"ok with default inputs":            {params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier}, false},
+		"ok with 0 init delay":              {params{0, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier}, false},
+		"ok with 0 exp limit":               {params{defaultInitDelay, defaultBaseDelay, 0, defaultJitterFactor, defaultMultiplier}, false},
+		"ok with 0 jitter factor":           {params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 0, defaultMultiplier}, false},
+		"fails with negative init delay":    {params{-1, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier}, true},
+		"fails with negative base delay":    {params{defaultInitDelay, -1, defaultExpLimit, defaultJitterFactor, defaultMultiplier}, true},
+		"fails with 0 base delay":           {params{defaultInitDelay, 0, defaultExpLimit, defaultJitterFactor, defaultMultiplier}, true},
+		"fails with negative exp limit":     {params{defaultInitDelay, defaultBaseDelay, -1, defaultJitterFactor, defaultMultiplier}, true},
+		"fails with negative jitter factor": {params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, -1, defaultMultiplier}, true},
+		"ok with jitter factor == 1":        {params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 1, defaultMultiplier}, false},
+		"fails with jitter factor > 1":      {params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 1.3, defaultMultiplier}, true},
+		"ok with 1.5 multiplier":            {params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, 1.5}, false},
+		"fails with multiplier == 1":        {params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, 1}, true},
+		"fails with multiplier < 1":         {params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, 0.5}, true},
 	}
 
 	for name, tc := range tests {
@@ -34,6 +50,7 @@ func TestNewConstructor(t *testing.T) {
 				WithBaseDelay(tc.inputs.baseDelay),
 				WithExponentialLimit(tc.inputs.expLimit),
 				WithJitterFactor(tc.inputs.jitterFactor),
+				WithMultiplier(tc.inputs.multiplier),
 			)
 			if err == nil && tc.expectErr {
 				t.Fatalf("expected error but received none")
@@ -45,54 +62,90 @@ func TestNewConstructor(t *testing.T) {
 
 }
 
+func TestNewErrorsIs(t *testing.T) {
+	_, err := New(
+		WithInitialDelay(-1),
+		WithBaseDelay(0),
+		WithExponentialLimit(-1),
+		WithJitterFactor(1.5),
+	)
+	for _, target := range []error{ErrInvalidInitialDelay, ErrInvalidBaseDelay, ErrInvalidExpLimit, ErrInvalidJitterFactor} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected %v in %v", target, err)
+		}
+	}
+
+	_, err = New(WithBaseDelay(0))
+	if !errors.Is(err, ErrInvalidBaseDelay) || errors.Is(err, ErrInvalidInitialDelay) {
+		t.Fatalf("expected only %v, got %v", ErrInvalidBaseDelay, err)
+	}
+
+	_, err = New(WithAbsoluteJitter(time.Millisecond), WithJitterFactor(0.2))
+	if !errors.Is(err, ErrInvalidJitterFactor) {
+		t.Fatalf("expected %v, got %v", ErrInvalidJitterFactor, err)
+	}
+}
+
 func TestCoerceNewConstructor(t *testing.T) {
 	tests := map[string]struct {
-		inputs  Backoff
-		outputs Backoff
+		inputs  params
+		outputs params
 	}{
 		"with default inputs": {
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
 		},
 		"with 0 init delay": {
-			Backoff{0, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
-			Backoff{0, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
+			params{0, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
+			params{0, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
 		},
 		"with 0 exp limit": {
-			Backoff{defaultInitDelay, defaultBaseDelay, 0, defaultJitterFactor},
-			Backoff{defaultInitDelay, defaultBaseDelay, 0, defaultJitterFactor},
+			params{defaultInitDelay, defaultBaseDelay, 0, defaultJitterFactor, defaultMultiplier},
+			params{defaultInitDelay, defaultBaseDelay, 0, defaultJitterFactor, defaultMultiplier},
 		},
 		"with 0 jitter factor": {
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 0},
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 0},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 0, defaultMultiplier},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 0, defaultMultiplier},
 		},
 		"coerce negative init delay to 0": {
-			Backoff{-1, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
-			Backoff{0, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
+			params{-1, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
+			params{0, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
 		},
 		"coerce negative base delay to the default": {
-			Backoff{defaultInitDelay, -1, defaultExpLimit, defaultJitterFactor},
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
+			params{defaultInitDelay, -1, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
 		},
 		"coerce 0 base delay to the default": {
-			Backoff{defaultInitDelay, 0, defaultExpLimit, defaultJitterFactor},
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
+			params{defaultInitDelay, 0, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
 		},
 		"coerce negative exp limit to 0": {
-			Backoff{defaultInitDelay, defaultBaseDelay, -1, defaultJitterFactor},
-			Backoff{defaultInitDelay, defaultBaseDelay, 0, defaultJitterFactor},
+			params{defaultInitDelay, defaultBaseDelay, -1, defaultJitterFactor, defaultMultiplier},
+			params{defaultInitDelay, defaultBaseDelay, 0, defaultJitterFactor, defaultMultiplier},
 		},
 		"coerce negative jitter factor to zero": {
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, -1},
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 0},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, -1, defaultMultiplier},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 0, defaultMultiplier},
 		},
-		"coerce jitter factor == 1 to the default": {
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 1},
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
+		"with jitter factor == 1": {
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 1, defaultMultiplier},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 1, defaultMultiplier},
 		},
 		"coerce jitter factor > 1 to the default": {
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 1.3},
-			Backoff{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, 1.3, defaultMultiplier},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
+		},
+		"with 3 multiplier": {
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, 3},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, 3},
+		},
+		"coerce multiplier == 1 to the default": {
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, 1},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
+		},
+		"coerce multiplier < 1 to the default": {
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, -2},
+			params{defaultInitDelay, defaultBaseDelay, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
 		},
 	}
 	for name, tc := range tests {
@@ -104,9 +157,11 @@ func TestCoerceNewConstructor(t *testing.T) {
 				WithBaseDelay(tc.inputs.baseDelay),
 				WithExponentialLimit(tc.inputs.expLimit),
 				WithJitterFactor(tc.inputs.jitterFactor),
+				WithMultiplier(tc.inputs.multiplier),
 			)
-			if !reflect.DeepEqual(tc.outputs, *b) {
-				t.Fatalf("got: %+v, want: %+v", b, &tc.outputs)
+			got := params{b.delay, b.baseDelay, b.expLimit, b.jitterFactor, b.multiplier}
+			if !reflect.DeepEqual(tc.outputs, got) {
+				t.Fatalf("got: %+v, want: %+v", got, tc.outputs)
 			}
 		})
 	}
@@ -115,15 +170,15 @@ func TestCoerceNewConstructor(t *testing.T) {
 
 func TestBaseDelay(t *testing.T) {
 	tests := map[string]struct {
-		inputs      Backoff
+		inputs      params
 		round2Delay time.Duration
 	}{
 		"uses baseDelay if initial delay is 0": {
-			Backoff{0, 200, defaultExpLimit, defaultJitterFactor},
+			params{0, 200, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
 			200,
 		},
 		"ignores baseDelay if initial delay is not 0": {
-			Backoff{1, 200, defaultExpLimit, defaultJitterFactor},
+			params{1, 200, defaultExpLimit, defaultJitterFactor, defaultMultiplier},
 			2,
 		},
 	}
@@ -136,6 +191,7 @@ func TestBaseDelay(t *testing.T) {
 				WithBaseDelay(tc.inputs.baseDelay),
 				WithExponentialLimit(tc.inputs.expLimit),
 				WithJitterFactor(tc.inputs.jitterFactor),
+				WithMultiplier(tc.inputs.multiplier),
 			)
 			b.computeDelay()
 			r2d := b.delay
@@ -176,7 +232,7 @@ func TestGrowthAndJitter(t *testing.T) {
 
 	// confirm exponential growth; 2 4 8 16 32 64
 	for i := 1; i <= reachesLimitAt; i++ {
-		if expected := 2 * delays[i-1]; delays[i] != expected {
+		if expected := time.Duration(float64(delays[i-1]) * b.multiplier); delays[i] != expected {
 			t.Fatalf("exponential growth violation, expected: %v, got %v", expected, delays[i])
 		}
 	}
@@ -200,3 +256,1785 @@ func TestGrowthAndJitter(t *testing.T) {
 		t.Fatalf("jitter failure: all delays with jitter applied: %v", delaysWithJitter)
 	}
 }
+
+func TestMultiplier(t *testing.T) {
+	tests := map[string]struct {
+		multiplier float64
+		want       []time.Duration
+	}{
+		"gentle":     {1.5, []time.Duration{1000, 1500, 2250, 3375, 5062, 7593, 11389, 11389}},
+		"default":    {2, []time.Duration{1000, 2000, 4000, 8000, 16000, 16000}},
+		"aggressive": {3, []time.Duration{1000, 3000, 9000, 27000, 27000}},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			b := CoerceNew(
+				WithInitialDelay(1000),
+				WithExponentialLimit(10000),
+				WithJitterFactor(0),
+				WithMultiplier(tc.multiplier),
+			)
+			if got := b.schedule(len(tc.want)); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConstantFallback(t *testing.T) {
+	const (
+		n        = 20000
+		prob     = 0.3
+		constant = time.Duration(3)
+	)
+	b := CoerceNew(
+		WithInitialDelay(1000),
+		WithExponentialLimit(1000),
+		WithJitterFactor(0),
+		WithConstantFallback(prob, constant),
+	)
+	nConstant := 0
+	for i := 0; i < n; i++ {
+		switch d := b.computeDelay(); d {
+		case constant:
+			nConstant++
+		case 1000:
+		default:
+			t.Fatalf("unexpected delay: %v", d)
+		}
+	}
+	if got := float64(nConstant) / n; math.Abs(got-prob) > 0.02 {
+		t.Fatalf("constant fallback rate, expected ~%v, got %v", prob, got)
+	}
+
+	// falling back must not disturb exponential growth
+	b = CoerceNew(
+		WithInitialDelay(2),
+		WithJitterFactor(0),
+		WithConstantFallback(1, constant),
+	)
+	for i := 0; i < 5; i++ {
+		if d := b.computeDelay(); d != constant {
+			t.Fatalf("expected constant delay %v, got %v", constant, d)
+		}
+	}
+	if b.delay != 64 {
+		t.Fatalf("expected growth state of %v, got %v", 64, b.delay)
+	}
+}
+
+func TestLimitAsFloor(t *testing.T) {
+	var lim time.Duration = 1000
+	b := CoerceNew(
+		WithInitialDelay(lim/4),
+		WithExponentialLimit(lim),
+		WithLimitAsFloor(),
+	)
+
+	// jitter is centered before the plateau
+	below := false
+	for i := 0; i < 100; i++ {
+		if b.clone().computeDelay() < lim/4 {
+			below = true
+			break
+		}
+	}
+	if !below {
+		t.Fatalf("expected centered jitter before reaching the limit")
+	}
+
+	b.computeDelay()
+	b.computeDelay()
+	if !b.atLimit() {
+		t.Fatalf("expected the backoff to be at the limit, delay: %v", b.delay)
+	}
+	if lo, hi := b.PeekRange(); lo != lim || hi != lim+lim*3/10 {
+		t.Fatalf("got range: [%v, %v], want: [%v, %v]", lo, hi, lim, lim+lim*3/10)
+	}
+	for i := 0; i < 1000; i++ {
+		if d := b.computeDelay(); d < lim {
+			t.Fatalf("delay %v fell below the limit %v", d, lim)
+		}
+	}
+}
+
+func TestBaseDelayAlways(t *testing.T) {
+	tests := map[string]struct {
+		options []backoffOption
+		want    []time.Duration
+	}{
+		"default grows from the initial delay": {
+			[]backoffOption{WithInitialDelay(50), WithBaseDelay(500)},
+			[]time.Duration{50, 100, 200, 400},
+		},
+		"grows from the base delay": {
+			[]backoffOption{WithInitialDelay(50), WithBaseDelay(500), WithBaseDelayAlways()},
+			[]time.Duration{50, 500, 1000, 2000},
+		},
+		"same as default when the initial delay is 0": {
+			[]backoffOption{WithInitialDelay(0), WithBaseDelay(500), WithBaseDelayAlways()},
+			[]time.Duration{0, 500, 1000, 2000},
+		},
+		"base delay equal to the initial delay": {
+			[]backoffOption{WithInitialDelay(500), WithBaseDelay(500), WithBaseDelayAlways()},
+			[]time.Duration{500, 500, 1000, 2000},
+		},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			b := CoerceNew(append(tc.options, WithJitterFactor(0))...)
+			got := make([]time.Duration, len(tc.want))
+			for i := range got {
+				got[i] = b.computeDelay()
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReplayBackoff(t *testing.T) {
+	recorded := []time.Duration{5, 100, 30, 2000}
+	b := ReplayBackoff(recorded)
+	for i, want := range recorded {
+		if d := b.computeDelay(); d != want {
+			t.Fatalf("round %d: got %v, want %v", i, d, want)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if d := b.computeDelay(); d != 2000 {
+			t.Fatalf("expected the last delay to be held, got %v", d)
+		}
+	}
+
+	// the replay is independent of the caller's slice
+	recorded[0] = 1
+	if d := b.clone().computeDelay(); d != 5 {
+		t.Fatalf("expected replay to start from the recorded delay, got %v", d)
+	}
+
+	if d := ReplayBackoff(nil).computeDelay(); d != 0 {
+		t.Fatalf("expected 0 delay for an empty replay, got %v", d)
+	}
+}
+
+func TestMaxAttemptsCountsOnlySleeps(t *testing.T) {
+	const maxAttempts = 3
+	b := CoerceNew(WithInitialDelay(1), WithMaxAttempts(maxAttempts))
+	for i := 0; i < 100; i++ {
+		b.PeekDelay()
+		b.PeekRange()
+	}
+	for i := 0; i < maxAttempts; i++ {
+		if b.Done() {
+			t.Fatalf("exhausted after %d sleeps, expected %d", i, maxAttempts)
+		}
+		b.Sleep()
+		b.PeekDelay()
+	}
+	if !b.Done() {
+		t.Fatalf("expected to be done after %d sleeps", maxAttempts)
+	}
+
+	// further sleeps neither pause nor advance the backoff
+	d := b.PeekDelay()
+	b.Sleep()
+	if b.PeekDelay() != d || b.attempts != maxAttempts {
+		t.Fatalf("sleep advanced an exhausted backoff")
+	}
+
+	if b := CoerceNew(WithInitialDelay(1)); b.Done() {
+		t.Fatalf("expected no limit by default")
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	tests := map[string]struct {
+		advance func(b *Backoff)
+	}{
+		"sleep": {func(b *Backoff) { b.Sleep() }},
+		"next":  {func(b *Backoff) { b.Next() }},
+		"mixed": {func(b *Backoff) {
+			if b.attempts%2 == 0 {
+				b.Sleep()
+			} else {
+				b.Next()
+			}
+		}},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			b := CoerceNew(WithInitialDelay(1), WithMaxAttempts(4))
+			n := 0
+			for ; !b.Done(); n++ {
+				tc.advance(b)
+			}
+			if n != 4 {
+				t.Fatalf("expected to be done after 4 attempts, got %d", n)
+			}
+		})
+	}
+
+	if _, err := New(WithMaxAttempts(-1)); err == nil {
+		t.Fatalf("expected an error for a negative limit")
+	}
+	if b := CoerceNew(WithMaxAttempts(-1)); b.maxAttempts != 0 {
+		t.Fatalf("expected a negative limit to be coerced to none, got %d", b.maxAttempts)
+	}
+}
+
+func TestScaled(t *testing.T) {
+	parent := CoerceNew(
+		WithInitialDelay(0),
+		WithBaseDelay(time.Second),
+		WithExponentialLimit(time.Second*30),
+		WithJitterFactor(0.2),
+	)
+	parent.computeDelay()
+
+	const factor = 0.1
+	child := parent.Scaled(factor)
+	if child.baseDelay != time.Millisecond*100 {
+		t.Fatalf("expected child base delay of 100ms, got %v", child.baseDelay)
+	}
+	if child.jitterFactor != parent.jitterFactor {
+		t.Fatalf("expected jitter factor to be preserved, got %v", child.jitterFactor)
+	}
+	want, got := parent.schedule(10), child.schedule(10)
+	for i := range want {
+		if scaled := time.Duration(float64(want[i]) * factor); got[i] != scaled {
+			t.Fatalf("round %d: got %v, want %v", i, got[i], scaled)
+		}
+	}
+
+	// the child starts fresh and is independent of the parent
+	if child.delay != 0 {
+		t.Fatalf("expected child in its initial state, got delay %v", child.delay)
+	}
+	child.computeDelay()
+	if parent.delay != time.Second {
+		t.Fatalf("advancing the child advanced the parent")
+	}
+
+	// the floor and the other delay-valued settings are scaled too, so the
+	// child remains valid
+	floored := CoerceNew(
+		WithInitialDelay(time.Millisecond*50),
+		WithBaseDelay(time.Millisecond*100),
+		WithExponentialLimit(time.Millisecond*100),
+		WithMinDelay(time.Millisecond*50),
+		WithStartupSpread(time.Second),
+		WithPlateauPause(3, time.Minute),
+	)
+	small := floored.Scaled(factor)
+	if small.minDelay != time.Millisecond*5 || small.startupSpread != time.Millisecond*100 || small.pauseDelay != time.Second*6 {
+		t.Fatalf("expected the floor, spread and pause to be scaled, got %v, %v, %v", small.minDelay, small.startupSpread, small.pauseDelay)
+	}
+	if err := small.reconcile(false); err != nil {
+		t.Fatalf("expected a valid child, got %v", err)
+	}
+
+	// invalid factors are coerced to 1
+	for _, f := range []float64{0, -1, math.NaN()} {
+		if c := parent.Scaled(f); c.baseDelay != parent.baseDelay {
+			t.Fatalf("factor %v: expected unscaled base delay, got %v", f, c.baseDelay)
+		}
+	}
+}
+
+func TestObserveLatency(t *testing.T) {
+	b := CoerceNew(WithLatencyMultiplier(3))
+	b.ObserveLatency(time.Millisecond * 100)
+	if b.baseDelay != time.Millisecond*300 {
+		t.Fatalf("expected the first sample to seed the base delay, got %v", b.baseDelay)
+	}
+
+	// a step change is approached smoothly, with the expected time constant
+	prev := b.baseDelay
+	for n := 1; n <= 10; n++ {
+		b.ObserveLatency(time.Millisecond * 200)
+		avg := 200 - 100*math.Pow(1-latencySmoothing, float64(n))
+		want := time.Duration(math.Round(avg * 3 * float64(time.Millisecond)))
+		if diff := b.baseDelay - want; diff < -1 || diff > 1 {
+			t.Fatalf("sample %d: got base delay %v, want %v", n, b.baseDelay, want)
+		}
+		if b.baseDelay <= prev || b.baseDelay >= time.Millisecond*600 {
+			t.Fatalf("sample %d: base delay %v did not rise smoothly from %v", n, b.baseDelay, prev)
+		}
+		prev = b.baseDelay
+	}
+
+	// the base delay drives growth after an immediate first retry
+	b = CoerceNew(WithInitialDelay(0), WithJitterFactor(0))
+	b.ObserveLatency(time.Millisecond * 40)
+	b.computeDelay()
+	if b.delay != time.Millisecond*40 {
+		t.Fatalf("expected growth from the observed latency, got %v", b.delay)
+	}
+
+	if _, err := New(WithLatencyMultiplier(0)); err == nil {
+		t.Fatalf("expected error for a latency multiplier of 0")
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Millisecond), WithJitterFactor(0))
+	if err := b.SleepContext(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.delay != time.Millisecond*2 {
+		t.Fatalf("expected the backoff to advance, got delay %v", b.delay)
+	}
+
+	// cancelled mid-sleep, and still advanced
+	b = CoerceNew(WithInitialDelay(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := b.SleepContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if b.attempts != 1 {
+		t.Fatalf("expected the backoff to advance, got %d attempts", b.attempts)
+	}
+}
+
+func TestSleepContextAlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b := CoerceNew()
+	d := b.PeekDelay()
+	if err := b.SleepContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if b.PeekDelay() != d || b.attempts != 0 {
+		t.Fatalf("expected no advance, got delay %v, attempts %d", b.PeekDelay(), b.attempts)
+	}
+
+	b = CoerceNew(WithAdvanceOnCancel(true))
+	if err := b.SleepContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if b.PeekDelay() != d*2 || b.attempts != 1 {
+		t.Fatalf("expected an advance, got delay %v, attempts %d", b.PeekDelay(), b.attempts)
+	}
+}
+
+func TestDelayOverride(t *testing.T) {
+	var attempts []int
+	b := CoerceNew(WithDelayOverride(func(attempt int, planned time.Duration) time.Duration {
+		attempts = append(attempts, attempt)
+		return time.Second
+	}))
+	lo, hi := time.Millisecond*850, time.Millisecond*1150
+	jittered := false
+	for i := 0; i < 20; i++ {
+		d := b.computeDelay()
+		if d < lo || d > hi {
+			t.Fatalf("expected a jittered ~1s delay, got %v", d)
+		}
+		jittered = jittered || d != time.Second
+	}
+	if !jittered {
+		t.Fatalf("expected jitter to be applied to the overridden delay")
+	}
+	for i, a := range attempts {
+		if a != i+1 {
+			t.Fatalf("expected attempt numbers counting from 1, got %v", attempts)
+		}
+	}
+
+	// growth continues underneath the override
+	if want := defaultInitDelay << 11; b.delay != want {
+		t.Fatalf("expected growth state of %v, got %v", want, b.delay)
+	}
+
+	// returning the planned delay is a no-op
+	b = CoerceNew(
+		WithJitterFactor(0),
+		WithDelayOverride(func(_ int, planned time.Duration) time.Duration { return planned }),
+	)
+	if d := b.computeDelay(); d != defaultInitDelay {
+		t.Fatalf("got: %v, want: %v", d, defaultInitDelay)
+	}
+}
+
+func TestGrowthStalled(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(1))
+	if b.GrowthStalled() {
+		t.Fatalf("default multiplier reported as stalled")
+	}
+
+	b.multiplier = 1.0001
+	if !b.GrowthStalled() {
+		t.Fatalf("expected a 1ns delay with a 1.0001x multiplier to be stalled")
+	}
+	if d := b.plateauDelay(); d != 1 {
+		t.Fatalf("expected a stalled plateau at 1ns, got %v", d)
+	}
+
+	// growth stopping at the limit is not a stall
+	b = CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second))
+	if !b.atLimit() || b.GrowthStalled() {
+		t.Fatalf("reaching the limit reported as stalled")
+	}
+
+	// an immediate first retry grows from the base delay
+	b = CoerceNew(WithInitialDelay(0))
+	b.multiplier = 1.0001
+	if b.GrowthStalled() {
+		t.Fatalf("immediate first retry reported as stalled")
+	}
+}
+
+func TestPlateauPause(t *testing.T) {
+	const (
+		lim   = time.Duration(8)
+		pause = time.Duration(1000)
+	)
+	b := CoerceNew(
+		WithInitialDelay(2),
+		WithExponentialLimit(lim),
+		WithJitterFactor(0),
+		WithPlateauPause(3, pause),
+	)
+	want := []time.Duration{2, 4, 8, 8, 8, pause, 8, 8, 8, pause, 8}
+	got := make([]time.Duration, len(want))
+	for i := range got {
+		got[i] = b.computeDelay()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+
+	if _, err := New(WithPlateauPause(0, pause)); err == nil {
+		t.Fatalf("expected error for 0 plateau rounds")
+	}
+}
+
+func TestGlobalSlots(t *testing.T) {
+	const (
+		slot   = time.Second
+		offset = time.Millisecond * 250
+	)
+	now := time.Unix(1700000000, int64(time.Millisecond*600))
+	b := CoerceNew(WithGlobalSlots(slot, offset))
+	b.clock = nowClock(func() time.Time { return now })
+
+	for i := 0; i < 5; i++ {
+		d := b.computeDelay()
+		target := now.Add(d)
+		if since := target.UnixNano() - int64(offset); since%int64(slot) != 0 {
+			t.Fatalf("target %v is not on a slot boundary plus the offset", target)
+		}
+		if d <= offset || d > slot+offset {
+			t.Fatalf("expected the next slot, got a delay of %v", d)
+		}
+		now = target.Add(time.Millisecond * 30 * time.Duration(i))
+	}
+	if b.attempts != 5 {
+		t.Fatalf("expected attempts to be counted, got %d", b.attempts)
+	}
+
+	if _, err := New(WithGlobalSlots(0, offset)); err == nil {
+		t.Fatalf("expected error for a slot of 0")
+	}
+}
+
+func TestInitialDelay(t *testing.T) {
+	tests := map[string]struct {
+		b    *Backoff
+		want time.Duration
+	}{
+		"default":    {CoerceNew(), defaultInitDelay},
+		"configured": {CoerceNew(WithInitialDelay(time.Second)), time.Second},
+		"coerced":    {CoerceNew(WithInitialDelay(-1)), 0},
+	}
+	for name, tc := range tests {
+		tc.b.NextDelay()
+		tc.b.NextDelay()
+		if got := tc.b.InitialDelay(); got != tc.want {
+			t.Fatalf("%s: got: %v, want: %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestSetInitialDelay(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Second))
+	b.SetInitialDelay(time.Millisecond * 10)
+	if b.PeekDelay() != time.Millisecond*10 {
+		t.Fatalf("expected the new initial delay to apply immediately, got %v", b.PeekDelay())
+	}
+
+	b.computeDelay()
+	b.SetInitialDelay(time.Millisecond * 50)
+	if b.PeekDelay() != time.Millisecond*20 {
+		t.Fatalf("expected an advanced backoff to be unchanged, got %v", b.PeekDelay())
+	}
+	b.reset()
+	if b.PeekDelay() != time.Millisecond*50 {
+		t.Fatalf("expected a reset to use the new initial delay, got %v", b.PeekDelay())
+	}
+
+	b.SetInitialDelay(-1)
+	if b.initDelay != 0 || b.PeekDelay() != 0 {
+		t.Fatalf("expected a negative initial delay to be coerced to 0")
+	}
+
+	b = CoerceNew(WithInitialDelay(time.Second), WithSchedule(time.Millisecond), WithJitterFactor(0))
+	b.Next()
+	b.SetInitialDelay(time.Millisecond * 10)
+	if got := b.Next(); got != time.Second {
+		t.Fatalf("expected a backoff advanced by a scripted round to be unchanged, got %v", got)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Millisecond*10), WithJitterFactor(0), WithMaxDelay(time.Second))
+
+	b.Override(time.Millisecond * 500)
+	if got := b.Next(); got != time.Millisecond*500 {
+		t.Fatalf("got %v, want the override", got)
+	}
+	if got := b.Next(); got != time.Millisecond*20 {
+		t.Fatalf("got %v, want growth to continue as planned", got)
+	}
+
+	b.Override(time.Minute)
+	if got := b.Next(); got != time.Second {
+		t.Fatalf("got %v, want the override capped by the max delay", got)
+	}
+
+	b.Override(time.Millisecond * 500)
+	b.Reset()
+	if got := b.Next(); got != time.Millisecond*10 {
+		t.Fatalf("got %v, want Reset to clear the override", got)
+	}
+
+	// jitter only increases the override
+	j := CoerceNew(WithJitterFactor(0.5))
+	for i := 0; i < 100; i++ {
+		j.Override(time.Second)
+		if got := j.Next(); got < time.Second || got > time.Millisecond*1500 {
+			t.Fatalf("got %v, want within [1s, 1.5s]", got)
+		}
+	}
+}
+
+func TestAdvanceConcurrent(t *testing.T) {
+	t.Parallel()
+
+	const callers, calls = 8, 100
+	b := CoerceNew()
+	seen := make([]bool, callers*calls+1)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < calls; j++ {
+				d, attempt := b.Advance()
+				if d < 0 || d > defaultExpLimit*2 {
+					t.Errorf("unexpected delay %v", d)
+				}
+				mu.Lock()
+				if attempt < 1 || attempt >= len(seen) || seen[attempt] {
+					t.Errorf("unexpected or duplicate attempt %d", attempt)
+				} else {
+					seen[attempt] = true
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if b.attempts != callers*calls {
+		t.Fatalf("expected %d attempts, got %d", callers*calls, b.attempts)
+	}
+}
+
+func TestOnExhausted(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy    ExhaustedPolicy
+		wantSleep bool
+		wantPanic bool
+	}{
+		"noop":          {ExhaustedNoop, false, false},
+		"sleep plateau": {ExhaustedSleepPlateau, true, false},
+		"panic":         {ExhaustedPanic, false, true},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := New(
+				WithInitialDelay(time.Millisecond*20),
+				WithExponentialLimit(time.Millisecond*40),
+				WithJitterFactor(0),
+				WithMaxAttempts(1),
+				WithOnExhausted(tc.policy),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.Sleep()
+			if !b.Done() {
+				t.Fatal("expected the backoff to be done")
+			}
+
+			defer func() {
+				if r := recover(); (r != nil) != tc.wantPanic {
+					t.Fatalf("expected panic %t, got %v", tc.wantPanic, r)
+				}
+			}()
+			start := time.Now()
+			b.Sleep()
+			slept := time.Since(start) >= time.Millisecond*40
+			if slept != tc.wantSleep {
+				t.Fatalf("expected sleep %t, slept for %v", tc.wantSleep, time.Since(start))
+			}
+			if b.attempts != 1 {
+				t.Fatalf("expected the exhausted sleep not to advance, got %d attempts", b.attempts)
+			}
+		})
+	}
+
+	if _, err := New(WithOnExhausted(ExhaustedPolicy(7))); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
+
+func TestNextDelayStop(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Second), WithJitterFactor(0), WithMaxAttempts(2))
+	for _, want := range []time.Duration{time.Second, time.Second * 2, Stop, Stop} {
+		if d := b.NextDelay(); d != want {
+			t.Fatalf("got: %v, want: %v", d, want)
+		}
+	}
+	if b.attempts != 2 {
+		t.Fatalf("expected Stop not to advance, got %d attempts", b.attempts)
+	}
+
+	now := time.Now()
+	b = CoerceNew(WithJitterFactor(0))
+	b.maxElapsed = time.Minute
+	b.clock = nowClock(func() time.Time { return now })
+	if d := b.NextDelay(); d == Stop {
+		t.Fatal("expected a delay before the max elapsed time")
+	}
+	now = now.Add(time.Minute)
+	if d := b.NextDelay(); d != Stop {
+		t.Fatalf("expected Stop once the max elapsed time passed, got %v", d)
+	}
+}
+
+func TestTCPLike(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		initialRTO time.Duration
+		want       []time.Duration
+	}{
+		"rfc default": {0, []time.Duration{
+			time.Second, time.Second * 2, time.Second * 4, time.Second * 8,
+			time.Second * 16, time.Second * 32, time.Second * 60, time.Second * 60,
+		}},
+		"fast start": {time.Millisecond * 200, []time.Duration{
+			time.Millisecond * 200, time.Millisecond * 400, time.Millisecond * 800,
+		}},
+		"capped start": {time.Minute * 2, []time.Duration{time.Second * 60, time.Second * 60}},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b := TCPLike(tc.initialRTO)
+			if b.jitterFactor != 0 {
+				t.Fatalf("expected no jitter, got %v", b.jitterFactor)
+			}
+			if got := b.schedule(len(tc.want)); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		initialDelay time.Duration
+	}{
+		"initial delay":         {time.Second},
+		"immediate first retry": {0},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b := CoerceNew(WithInitialDelay(tc.initialDelay), WithJitterFactor(0), WithMaxAttempts(3))
+			want := b.schedule(3)
+			for !b.Done() {
+				b.NextDelay()
+			}
+
+			b.Reset()
+			if b.Done() || b.attempts != 0 || !b.start.IsZero() {
+				t.Fatalf("expected the attempts to be cleared")
+			}
+			var got []time.Duration
+			for d := b.NextDelay(); d != Stop; d = b.NextDelay() {
+				got = append(got, d)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestResetTo(t *testing.T) {
+	t.Parallel()
+
+	ms := time.Millisecond
+	tests := map[string]struct {
+		d    time.Duration
+		want []time.Duration
+	}{
+		"mid-sequence":    {ms * 400, []time.Duration{ms * 400, ms * 800, ms * 1600}},
+		"below the base":  {ms, []time.Duration{ms * 100, ms * 200, ms * 400}},
+		"above the limit": {time.Hour, []time.Duration{ms * 1000, ms * 1000, ms * 1000}},
+	}
+	for name, tc := range tests {
+		b := CoerceNew(WithExponentialLimit(time.Second), WithJitterFactor(0), WithMaxAttempts(3))
+		for !b.Done() {
+			b.Next()
+		}
+		b.ResetTo(tc.d)
+		if b.Done() || b.Attempts() != 0 {
+			t.Fatalf("%s: expected the attempts to be cleared", name)
+		}
+		var got []time.Duration
+		for d := b.NextDelay(); d != Stop; d = b.NextDelay() {
+			got = append(got, d)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+		}
+	}
+
+	// an exponential limit below the base delay clamps to the base delay
+	b := CoerceNew(WithExponentialLimit(0), WithJitterFactor(0))
+	b.ResetTo(time.Second)
+	if d := b.PeekDelay(); d != defaultBaseDelay {
+		t.Fatalf("expected %v, got %v", defaultBaseDelay, d)
+	}
+
+	// scheduled delays are skipped
+	s := CoerceNew(WithSchedule(ms, ms), WithJitterFactor(0))
+	s.ResetTo(ms * 300)
+	if d := s.Next(); d != ms*300 {
+		t.Fatalf("expected the scheduled delays to be skipped, got %v", d)
+	}
+}
+
+func TestNext(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Second), WithMaxAttempts(1))
+	for i := 0; i < 3; i++ {
+		lo, hi := b.PeekRange()
+		d := b.Next()
+		if d < lo || d > hi {
+			t.Fatalf("round %d: expected a jittered delay in [%v, %v], got %v", i, lo, hi, d)
+		}
+		if b.attempts != i+1 || b.PeekDelay() != b.progressAt(i+1).delay {
+			t.Fatalf("round %d: expected the backoff to advance, even past the max attempts", i)
+		}
+	}
+}
+
+func TestNextAt(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(0), WithBaseDelay(time.Second), WithJitterFactor(0))
+	b.clock = nowClock(func() time.Time { return now })
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{"fast handler", 0, time.Second},
+		{"slow handler", time.Millisecond * 300, time.Millisecond * 700},
+		{"overran the interval", time.Second * 2, 0},
+		{"wake time ahead", -time.Millisecond * 200, time.Millisecond * 1200},
+	}
+	for i, tt := range tests {
+		if d := b.NextAt(now.Add(-tt.elapsed)); d != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, d)
+		}
+		if b.Attempts() != i+1 {
+			t.Fatalf("%s: expected the backoff to advance", tt.name)
+		}
+	}
+
+	// the wake times stay on a fixed grid, however long each poll takes
+	start, wake := now, now
+	for i, poll := range []time.Duration{time.Millisecond * 100, time.Millisecond * 900, 0} {
+		now = now.Add(poll)
+		wake = now.Add(b.NextAt(wake))
+		if want := start.Add(time.Duration(i+1) * time.Second); !wake.Equal(want) {
+			t.Fatalf("round %d: expected to wake at %v, got %v", i, want, wake)
+		}
+		now = wake
+	}
+}
+
+func TestJitterMode(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithJitterMode(JitterFull))
+	if lo, hi := b.PeekRange(); lo != 0 || hi != time.Second {
+		t.Fatalf("expected full jitter across [0, 1s], got [%v, %v]", lo, hi)
+	}
+	var below bool
+	for i := 0; i < 100; i++ {
+		d := b.Next()
+		if d < 0 || d > time.Second {
+			t.Fatalf("expected a delay in [0, 1s], got %v", d)
+		}
+		below = below || d < time.Millisecond*425
+	}
+	if !below {
+		t.Fatalf("expected some delays below the proportional jitter band")
+	}
+
+	if JitterProportional != JitterSymmetric || CoerceNew().jitterMode != JitterProportional {
+		t.Fatalf("expected proportional jitter by default")
+	}
+	if _, err := New(WithJitterMode(JitterStrategy(-1))); err == nil {
+		t.Fatalf("expected an error for an unknown jitter mode")
+	}
+	if b := CoerceNew(WithJitterMode(JitterStrategy(-1))); b.jitterMode != JitterSymmetric {
+		t.Fatalf("expected an unknown jitter mode to be coerced to the default")
+	}
+}
+
+func TestWidestJitterFactor(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithJitterFactor(1))
+	if lo, hi := b.PeekRange(); lo != time.Millisecond*500 || hi != time.Millisecond*1500 {
+		t.Fatalf("expected jitter across [500ms, 1.5s], got [%v, %v]", lo, hi)
+	}
+	for i := 0; i < 100; i++ {
+		if d := b.Next(); d < time.Millisecond*500 || d > time.Millisecond*1500 {
+			t.Fatalf("expected a delay in [500ms, 1.5s], got %v", d)
+		}
+	}
+}
+
+func TestJitterBounds(t *testing.T) {
+	t.Parallel()
+
+	ms := time.Millisecond
+	tests := map[string]struct {
+		low, high float64
+		lo, hi    time.Duration
+	}{
+		"only longer":  {0, 0.3, ms * 1000, ms * 1300},
+		"only shorter": {-0.3, 0, ms * 700, ms * 1000},
+		"skewed":       {-0.1, 0.5, ms * 900, ms * 1500},
+		"down to 0":    {-1, 0, 0, ms * 1000},
+	}
+	for name, tc := range tests {
+		b, err := New(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithJitterBounds(tc.low, tc.high))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if lo, hi := b.PeekRange(); lo != tc.lo || hi != tc.hi {
+			t.Fatalf("%s: expected jitter across [%v, %v], got [%v, %v]", name, tc.lo, tc.hi, lo, hi)
+		}
+		for i := 0; i < 100; i++ {
+			if d := b.Next(); d < tc.lo || d > tc.hi {
+				t.Fatalf("%s: expected a delay in [%v, %v], got %v", name, tc.lo, tc.hi, d)
+			}
+		}
+	}
+
+	for name, bounds := range map[string][2]float64{
+		"low above high":  {0.3, 0.1},
+		"negative delays": {-1.5, 0},
+		"not a number":    {math.NaN(), 0},
+		"infinite spread": {0, math.Inf(1)},
+	} {
+		if _, err := New(WithJitterBounds(bounds[0], bounds[1])); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+		if b := CoerceNew(WithJitterBounds(bounds[0], bounds[1])); b.jitterBounds || b.jitterFactor != defaultJitterFactor {
+			t.Fatalf("%s: expected the default jitter to be kept", name)
+		}
+	}
+
+	for name, options := range map[string][]backoffOption{
+		"factor first":            {WithJitterFactor(0.2), WithJitterBounds(0, 0.3)},
+		"bounds first":            {WithJitterBounds(0, 0.3), WithJitterFactor(0.2)},
+		"explicit default factor": {WithJitterBounds(0, 0.3), WithJitterFactor(defaultJitterFactor)},
+		"absolute jitter first":   {WithAbsoluteJitter(time.Millisecond), WithJitterBounds(0, 0.3)},
+		"absolute jitter second":  {WithJitterBounds(0, 0.3), WithAbsoluteJitter(time.Millisecond)},
+	} {
+		if _, err := New(options...); !errors.Is(err, ErrInvalidJitterFactor) {
+			t.Fatalf("%s: expected %v, got %v", name, ErrInvalidJitterFactor, err)
+		}
+	}
+	if b := CoerceNew(WithJitterFactor(0.2), WithJitterBounds(0, 0.3)); b.jitterFactor != 0 || !b.jitterBounds {
+		t.Fatalf("expected the jitter bounds to replace the jitter factor")
+	}
+}
+
+func TestMaxJitterAbsolute(t *testing.T) {
+	t.Parallel()
+
+	limit := time.Minute * 3
+	tests := map[string]struct {
+		b      *Backoff
+		lo, hi time.Duration
+	}{
+		"caps proportional jitter": {
+			CoerceNew(WithInitialDelay(limit), WithExponentialLimit(limit), WithMaxJitterAbsolute(time.Second*5)),
+			limit - time.Second*5, limit + time.Second*5,
+		},
+		"caps full jitter": {
+			CoerceNew(WithInitialDelay(limit), WithExponentialLimit(limit), WithJitterMode(JitterFull), WithMaxJitterAbsolute(time.Second*5)),
+			limit - time.Second*5, limit,
+		},
+		"caps upward jitter at the floor": {
+			CoerceNew(WithInitialDelay(limit), WithExponentialLimit(limit), WithLimitAsFloor(), WithMaxJitterAbsolute(time.Second*5)),
+			limit, limit + time.Second*5,
+		},
+		"caps asymmetric jitter": {
+			CoerceNew(WithInitialDelay(limit), WithExponentialLimit(limit), WithJitterBounds(0, 0.5), WithMaxJitterAbsolute(time.Second*5)),
+			limit, limit + time.Second*5,
+		},
+		"leaves small delays alone": {
+			CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithMaxJitterAbsolute(time.Second*5)),
+			time.Millisecond * 850, time.Millisecond * 1150,
+		},
+	}
+	for name, tc := range tests {
+		if lo, hi := tc.b.PeekRange(); lo != tc.lo || hi != tc.hi {
+			t.Fatalf("%s: expected jitter across [%v, %v], got [%v, %v]", name, tc.lo, tc.hi, lo, hi)
+		}
+		for i := 0; i < 100; i++ {
+			if d := tc.b.Next(); d < tc.lo || d > tc.hi {
+				t.Fatalf("%s: expected a delay in [%v, %v], got %v", name, tc.lo, tc.hi, d)
+			}
+		}
+	}
+
+	if _, err := New(WithMaxJitterAbsolute(-1)); err == nil {
+		t.Fatalf("expected an error for a negative max jitter")
+	}
+	if b := CoerceNew(WithMaxJitterAbsolute(-1)); b.maxJitter != 0 {
+		t.Fatalf("expected a negative max jitter to be coerced to 0")
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	t.Parallel()
+
+	const base, limit = time.Millisecond * 100, time.Second * 2
+	b := CoerceNew(WithBaseDelay(base), WithExponentialLimit(limit), WithJitterMode(JitterDecorrelated))
+	if lo, hi := b.PeekRange(); lo != base || hi != base*3 {
+		t.Fatalf("expected the first delay in [%v, %v], got [%v, %v]", base, base*3, lo, hi)
+	}
+
+	prev, capped := base, false
+	for i := 0; i < 200; i++ {
+		lo, hi := b.PeekRange()
+		d := b.Next()
+		if want := min(prev*3, limit); lo != base || hi != want {
+			t.Fatalf("round %d: expected the range [%v, %v], got [%v, %v]", i, base, want, lo, hi)
+		}
+		if d < lo || d > hi {
+			t.Fatalf("round %d: expected a delay in [%v, %v], got %v", i, lo, hi, d)
+		}
+		if d > b.WorstCaseDelay(i) {
+			t.Fatalf("round %d: delay %v exceeds the worst case %v", i, d, b.WorstCaseDelay(i))
+		}
+		prev, capped = d, capped || hi == limit
+	}
+	if !capped {
+		t.Fatalf("expected the delays to reach the exponential limit")
+	}
+
+	b.Reset()
+	if _, hi := b.PeekRange(); hi != base*3 {
+		t.Fatalf("expected a reset to forget the previous delay, got %v", hi)
+	}
+	if got := []time.Duration{b.WorstCaseDelay(0), b.WorstCaseDelay(1), b.WorstCaseDelay(2)}; !reflect.DeepEqual(got, []time.Duration{base * 3, base * 9, limit}) {
+		t.Fatalf("unexpected worst cases: %v", got)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	t.Parallel()
+
+	const goroutines, rounds = 8, 200
+	b := CoerceNew(WithInitialDelay(time.Millisecond), WithHistory(16))
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				b.Next()
+				switch j % 4 {
+				case 0:
+					b.PeekRange()
+					b.Done()
+				case 1:
+					b.ObserveLatency(time.Millisecond * time.Duration(i+1))
+					b.SteadyStateRate()
+				case 2:
+					b.Fingerprint()
+					b.History()
+				case 3:
+					b.WorstCaseDelay(j)
+					b.Scaled(2)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if b.attempts != goroutines*rounds {
+		t.Fatalf("expected %d attempts, got %d", goroutines*rounds, b.attempts)
+	}
+}
+
+func TestJitterSeed(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Second), WithJitterSeed(7))
+	want := []time.Duration{1125667648, 1838904304, 3689665080}
+	for i, w := range want {
+		if d := b.Next(); d != w {
+			t.Fatalf("round %d: expected the seeded delay %v, got %v", i, w, d)
+		}
+	}
+
+	r := CoerceNew(WithInitialDelay(time.Second), WithRandSource(rand.New(rand.NewSource(7))))
+	if d := r.Next(); d != want[0] {
+		t.Fatalf("expected the same delays as an explicitly seeded source, got %v", d)
+	}
+}
+
+func TestRandSource(t *testing.T) {
+	t.Parallel()
+
+	seeded := func() *Backoff {
+		return CoerceNew(
+			WithConstantFallback(0.5, time.Second),
+			WithRandSource(rand.New(rand.NewSource(42))),
+		)
+	}
+	b1, b2 := seeded(), seeded()
+	for i := 0; i < 20; i++ {
+		if d1, d2 := b1.Next(), b2.Next(); d1 != d2 {
+			t.Fatalf("round %d: expected equal delays with the same seed, got %v and %v", i, d1, d2)
+		}
+	}
+
+	c1, c2 := seeded().Scaled(1), seeded().Scaled(1)
+	for i := 0; i < 20; i++ {
+		if d1, d2 := c1.Next(), c2.Next(); d1 != d2 {
+			t.Fatalf("round %d: expected derived backoffs to be deterministic, got %v and %v", i, d1, d2)
+		}
+	}
+
+	if _, err := New(WithRandSource(nil)); err == nil {
+		t.Fatalf("expected an error for a nil source")
+	}
+	if b := CoerceNew(WithRandSource(nil)); b.rng == nil {
+		t.Fatalf("expected a nil source to be coerced to the default")
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := map[string]struct {
+		b    *Backoff
+		want string
+	}{
+		"defaults": {
+			CoerceNew(),
+			"Backoff{initial=100ms, base=100ms, expLimit=3m0s, jitter=0.30}",
+		},
+		"configured": {
+			CoerceNew(WithInitialDelay(0), WithBaseDelay(time.Millisecond*500), WithExponentialLimit(time.Minute), WithJitterFactor(0.1)),
+			"Backoff{initial=0s, base=500ms, expLimit=1m0s, jitter=0.10}",
+		},
+		"non-default extras": {
+			CoerceNew(WithMultiplier(1.5), WithJitterMode(JitterFull), WithMaxAttempts(5)),
+			"Backoff{initial=100ms, base=100ms, expLimit=3m0s, jitter=0.30, multiplier=1.5, mode=full, maxAttempts=5}",
+		},
+		"named": {
+			CoerceNew(WithName("db")),
+			`Backoff{name="db", initial=100ms, base=100ms, expLimit=3m0s, jitter=0.30}`,
+		},
+		"tcp": {
+			TCPLike(time.Second),
+			"Backoff{initial=1s, base=1s, expLimit=1m0s, jitter=0.00, maxDelay=1m0s}",
+		},
+	}
+	for name, tc := range tests {
+		tc.b.Next()
+		if got := tc.b.String(); got != tc.want {
+			t.Fatalf("%s: got: %v, want: %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Second), WithMultiplier(3), WithJitterMode(JitterFull), WithMaxAttempts(4))
+	b.Next()
+	b.Next()
+
+	c := b.Clone()
+	if c.String() != b.String() {
+		t.Fatalf("expected the same configuration, got %v, want %v", c, b)
+	}
+	if c.PeekDelay() != time.Second || c.attempts != 0 {
+		t.Fatalf("expected the clone in its initial state, got delay %v, attempts %d", c.PeekDelay(), c.attempts)
+	}
+
+	for !c.Done() {
+		c.Next()
+	}
+	if b.attempts != 2 || b.PeekDelay() != time.Second*9 {
+		t.Fatalf("expected the original to be unaffected, got delay %v, attempts %d", b.PeekDelay(), b.attempts)
+	}
+}
+
+func TestMaxDelay(t *testing.T) {
+	t.Parallel()
+
+	const maxDelay = time.Millisecond * 1050
+	tests := map[string]*Backoff{
+		"jitter above the limit": CoerceNew(
+			WithInitialDelay(time.Second),
+			WithExponentialLimit(time.Second),
+			WithJitterFactor(0.9),
+			WithMaxDelay(maxDelay),
+		),
+		"growth past the limit": CoerceNew(WithInitialDelay(time.Millisecond*300), WithMaxDelay(maxDelay)),
+		"override": CoerceNew(
+			WithMaxDelay(maxDelay),
+			WithDelayOverride(func(int, time.Duration) time.Duration { return time.Hour }),
+		),
+	}
+	for name, b := range tests {
+		for i := 0; i < 100; i++ {
+			if _, hi := b.PeekRange(); hi > maxDelay {
+				t.Fatalf("%s: expected the range capped at %v, got %v", name, maxDelay, hi)
+			}
+			if d := b.Next(); d > maxDelay {
+				t.Fatalf("%s: round %d: expected at most %v, got %v", name, i, maxDelay, d)
+			}
+		}
+		if d := b.WorstCaseDelay(100); d > maxDelay {
+			t.Fatalf("%s: expected the worst case capped at %v, got %v", name, maxDelay, d)
+		}
+	}
+
+	if _, err := New(WithMaxDelay(-1)); err == nil {
+		t.Fatalf("expected an error for a negative max delay")
+	}
+	if b := CoerceNew(WithMaxDelay(-1)); b.maxDelay != 0 {
+		t.Fatalf("expected a negative max delay to be coerced to none")
+	}
+}
+
+func TestMinDelay(t *testing.T) {
+	t.Parallel()
+
+	const minDelay = time.Millisecond * 50
+	b := CoerceNew(WithInitialDelay(time.Millisecond*100), WithJitterMode(JitterFull), WithMinDelay(minDelay))
+	if lo, _ := b.PeekRange(); lo != minDelay {
+		t.Fatalf("expected the range floored at %v, got %v", minDelay, lo)
+	}
+	for i := 0; i < 100; i++ {
+		if d := b.Next(); d < minDelay {
+			t.Fatalf("round %d: expected at least %v, got %v", i, minDelay, d)
+		}
+	}
+	if d := CoerceNew(WithInitialDelay(0), WithMinDelay(minDelay)).Next(); d != minDelay {
+		t.Fatalf("expected an immediate retry to be floored at %v, got %v", minDelay, d)
+	}
+
+	tests := map[string]struct {
+		options []backoffOption
+		coerced time.Duration
+	}{
+		"negative":            {[]backoffOption{WithMinDelay(-1)}, 0},
+		"above the exp limit": {[]backoffOption{WithMinDelay(time.Second), WithExponentialLimit(time.Millisecond * 500)}, time.Millisecond * 500},
+		"above the max delay": {[]backoffOption{WithMaxDelay(time.Millisecond * 200), WithMinDelay(time.Second)}, time.Millisecond * 200},
+		"limit set before":    {[]backoffOption{WithExponentialLimit(0), WithMinDelay(1)}, 0},
+	}
+	for name, tc := range tests {
+		if _, err := New(tc.options...); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+		if b := CoerceNew(tc.options...); b.minDelay != tc.coerced {
+			t.Fatalf("%s: expected the min delay coerced to %v, got %v", name, tc.coerced, b.minDelay)
+		}
+	}
+}
+
+func TestMaxElapsed(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	b := CoerceNew(WithMaxElapsed(time.Minute))
+	b.clock = nowClock(func() time.Time { return now })
+
+	// construction does not start the clock
+	now = now.Add(time.Hour)
+	if b.Done() {
+		t.Fatalf("expected the clock to start at the first round")
+	}
+	b.Next()
+	now = now.Add(time.Second * 59)
+	if b.Done() {
+		t.Fatalf("expected the backoff not to be done within the budget")
+	}
+	now = now.Add(time.Second)
+	if !b.Done() {
+		t.Fatalf("expected the backoff to be done once the budget elapsed")
+	}
+
+	b.Reset()
+	if b.Done() {
+		t.Fatalf("expected a reset to restart the clock")
+	}
+
+	if _, err := New(WithMaxElapsed(-1)); err == nil {
+		t.Fatalf("expected an error for a negative limit")
+	}
+	if b := CoerceNew(WithMaxElapsed(-1)); b.maxElapsed != 0 {
+		t.Fatalf("expected a negative limit to be coerced to none")
+	}
+}
+
+func TestAfter(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Millisecond*20), WithJitterFactor(0))
+	start := time.Now()
+	select {
+	case <-b.After():
+	case <-time.After(time.Second):
+		t.Fatalf("expected the channel to fire")
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*20 {
+		t.Fatalf("expected the channel to fire after the delay, got %v", elapsed)
+	}
+
+	// dropped channels still advance the sequence
+	b.After()
+	if b.attempts != 2 || b.PeekDelay() != time.Millisecond*80 {
+		t.Fatalf("expected each call to advance, got delay %v, attempts %d", b.PeekDelay(), b.attempts)
+	}
+}
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Millisecond*20), WithJitterFactor(0))
+	start := time.Now()
+	select {
+	case <-b.Wait(context.Background()):
+	case <-time.After(time.Second):
+		t.Fatalf("expected the channel to close")
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*20 {
+		t.Fatalf("expected the channel to close after the delay, got %v", elapsed)
+	}
+
+	// the context closes the channel before a long delay
+	long := CoerceNew(WithInitialDelay(time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := long.Wait(ctx)
+	cancel()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the channel to close once the context is done")
+	}
+	if long.Attempts() != 1 {
+		t.Fatalf("expected Wait to advance, got %d attempts", long.Attempts())
+	}
+}
+
+func TestOnRetry(t *testing.T) {
+	t.Parallel()
+
+	type call struct {
+		attempt int
+		delay   time.Duration
+		op      any
+	}
+	var calls []call
+	var b *Backoff
+	b = CoerceNew(
+		WithInitialDelay(time.Millisecond),
+		WithMaxAttempts(4),
+		WithName("fetcher"),
+		WithOnRetry(func(name string, attempt int, delay time.Duration) {
+			if name != "fetcher" || b.Name() != name {
+				t.Errorf("expected the callback to get the name, got %q", name)
+			}
+			op, _ := b.Metadata("op")
+			calls = append(calls, call{attempt, delay, op})
+		}),
+	)
+	b.SetMetadata("op", "fetch")
+
+	var delays []time.Duration
+	delays = append(delays, b.Next())
+	delays = append(delays, b.NextDelay())
+	d, _ := b.Advance()
+	delays = append(delays, d)
+	lo, hi := b.PeekRange()
+	b.Sleep()
+	b.Sleep() // exhausted, so not a round
+
+	if len(calls) != 4 {
+		t.Fatalf("expected a call per round, got %v", calls)
+	}
+	for i, c := range calls {
+		if c.attempt != i+1 || c.op != "fetch" {
+			t.Fatalf("call %d: unexpected %+v", i, c)
+		}
+		if i < len(delays) && c.delay != delays[i] {
+			t.Fatalf("call %d: expected the jittered delay %v, got %v", i, delays[i], c.delay)
+		}
+	}
+	if last := calls[3].delay; last < lo || last > hi {
+		t.Fatalf("expected the slept delay in [%v, %v], got %v", lo, hi, last)
+	}
+
+	// no callback by default
+	CoerceNew().Next()
+}
+
+func TestAttempts(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Millisecond))
+	if b.Attempts() != 0 {
+		t.Fatalf("expected no attempts initially, got %d", b.Attempts())
+	}
+	b.Sleep()
+	b.Next()
+	b.PeekDelay()
+	b.PeekRange()
+	if b.Attempts() != 2 {
+		t.Fatalf("expected 2 attempts, got %d", b.Attempts())
+	}
+	b.Reset()
+	if b.Attempts() != 0 {
+		t.Fatalf("expected a reset to zero the attempts, got %d", b.Attempts())
+	}
+}
+
+func TestWithTemplate(t *testing.T) {
+	t.Parallel()
+
+	template := CoerceNew(
+		WithInitialDelay(time.Second),
+		WithExponentialLimit(time.Minute),
+		WithJitterFactor(0.1),
+		WithMaxAttempts(5),
+	)
+	template.Next()
+	template.Next()
+
+	b := CoerceNew(WithTemplate(template), WithMaxAttempts(10))
+	want := "Backoff{initial=1s, base=100ms, expLimit=1m0s, jitter=0.10, maxAttempts=10}"
+	if b.String() != want {
+		t.Fatalf("got: %v, want: %v", b, want)
+	}
+	if b.PeekDelay() != time.Second || b.Attempts() != 0 {
+		t.Fatalf("expected the template's initial delay, got %v", b.PeekDelay())
+	}
+	if template.maxAttempts != 5 {
+		t.Fatalf("expected the template to be unchanged")
+	}
+
+	if _, err := New(WithTemplate(nil)); err == nil {
+		t.Fatalf("expected an error for a nil template")
+	}
+	if b := CoerceNew(WithTemplate(nil)); b.String() != CoerceNew().String() {
+		t.Fatalf("expected a nil template to be ignored, got %v", b)
+	}
+}
+
+func TestTrySleep(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	elapsed := CoerceNew(WithInitialDelay(time.Millisecond), WithMaxElapsed(time.Minute))
+	elapsed.clock = nowClock(func() time.Time { return now })
+
+	tests := map[string]struct {
+		b       *Backoff
+		exhaust func()
+	}{
+		"max attempts": {
+			CoerceNew(WithInitialDelay(time.Millisecond), WithMaxAttempts(2), WithOnExhausted(ExhaustedPanic)),
+			func() {},
+		},
+		"max elapsed": {
+			elapsed,
+			func() { now = now.Add(time.Minute) },
+		},
+	}
+	for name, tc := range tests {
+		for i := 0; i < 2; i++ {
+			if err := tc.b.TrySleep(); err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+		}
+		tc.exhaust()
+		if err := tc.b.TrySleep(); !errors.Is(err, ErrBudgetExhausted) {
+			t.Fatalf("%s: expected %v, got %v", name, ErrBudgetExhausted, err)
+		}
+		if tc.b.Attempts() != 2 {
+			t.Fatalf("%s: expected no advance once exhausted, got %d attempts", name, tc.b.Attempts())
+		}
+	}
+}
+
+func TestEqualJitter(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithJitterMode(JitterEqual))
+	if lo, hi := b.PeekRange(); lo != time.Millisecond*500 || hi != time.Second {
+		t.Fatalf("expected equal jitter across [500ms, 1s], got [%v, %v]", lo, hi)
+	}
+	for i := 0; i < 100; i++ {
+		if d := b.Next(); d < time.Millisecond*500 || d > time.Second {
+			t.Fatalf("round %d: expected a delay in [500ms, 1s], got %v", i, d)
+		}
+	}
+}
+
+func TestAbsoluteJitter(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithAbsoluteJitter(time.Millisecond*50))
+	if lo, hi := b.PeekRange(); lo != time.Millisecond*950 || hi != time.Millisecond*1050 {
+		t.Fatalf("expected absolute jitter across [950ms, 1050ms], got [%v, %v]", lo, hi)
+	}
+	for i := 0; i < 100; i++ {
+		if d := b.Next(); d < time.Millisecond*950 || d > time.Millisecond*1050 {
+			t.Fatalf("round %d: expected a delay in [950ms, 1050ms], got %v", i, d)
+		}
+	}
+
+	// jitter larger than the delay never makes it negative
+	small := CoerceNew(WithInitialDelay(time.Millisecond), WithAbsoluteJitter(time.Second))
+	if lo, _ := small.PeekRange(); lo != 0 {
+		t.Fatalf("expected the jitter band to stop at 0, got %v", lo)
+	}
+
+	// an explicit jitter factor conflicts in either order, even the default
+	for _, jf := range []float64{0.2, defaultJitterFactor} {
+		if _, err := New(WithJitterFactor(jf), WithAbsoluteJitter(time.Millisecond)); !errors.Is(err, ErrInvalidJitterFactor) {
+			t.Fatalf("jitter factor %v: expected an error combining it with absolute jitter, got %v", jf, err)
+		}
+		if _, err := New(WithAbsoluteJitter(time.Millisecond), WithJitterFactor(jf)); !errors.Is(err, ErrInvalidJitterFactor) {
+			t.Fatalf("jitter factor %v: expected an error combining absolute jitter with it, got %v", jf, err)
+		}
+	}
+	for _, options := range [][]backoffOption{
+		{WithJitterFactor(0), WithAbsoluteJitter(time.Millisecond)},
+		{WithAbsoluteJitter(time.Millisecond), WithJitterFactor(0)},
+	} {
+		if _, err := New(options...); err != nil {
+			t.Fatalf("unexpected error with an explicit jitter factor of 0: %v", err)
+		}
+	}
+	if _, err := New(WithAbsoluteJitter(-1)); err == nil {
+		t.Fatal("expected an error for negative absolute jitter")
+	}
+	if c := CoerceNew(WithJitterFactor(0.2), WithAbsoluteJitter(time.Millisecond)); c.jitterFactor != 0 || c.absJitter != time.Millisecond {
+		t.Fatalf("expected the absolute jitter to be kept, got factor %v and absolute %v", c.jitterFactor, c.absJitter)
+	}
+}
+
+func TestDelayNeverNegative(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		b     *Backoff
+		force bool
+	}{
+		"absolute jitter beyond the delay": {CoerceNew(WithInitialDelay(time.Nanosecond), WithAbsoluteJitter(time.Hour)), false},
+		"full jitter with a floor":         {CoerceNew(WithInitialDelay(0), WithJitterMode(JitterFull), WithLimitAsFloor()), false},
+		"negative override": {CoerceNew(WithDelayOverride(func(int, time.Duration) time.Duration {
+			return -time.Hour
+		})), false},
+		"negative forced delay": {CoerceNew(WithAbsoluteJitter(time.Second)), true},
+	}
+	for name, tc := range tests {
+		for i := 0; i < 100; i++ {
+			if tc.force {
+				tc.b.Override(-time.Second)
+			}
+			if d := tc.b.Next(); d < 0 {
+				t.Fatalf("%s: round %d: got negative delay %v", name, i, d)
+			}
+		}
+	}
+}
+
+func TestStartupSpread(t *testing.T) {
+	t.Parallel()
+
+	spread := time.Millisecond * 50
+	b := CoerceNew(WithInitialDelay(time.Millisecond*10), WithJitterFactor(0), WithStartupSpread(spread))
+	if w := b.WorstCaseDelay(0); w != time.Millisecond*60 {
+		t.Fatalf("expected the worst case first delay to include the spread, got %v", w)
+	}
+
+	offsets := map[time.Duration]bool{}
+	for i := 0; i < 20; i++ {
+		c := b.Clone()
+		d := c.Next()
+		if d < time.Millisecond*10 || d > time.Millisecond*60 {
+			t.Fatalf("expected a first delay in [10ms, 60ms], got %v", d)
+		}
+		offsets[d] = true
+		if d := c.Next(); d != time.Millisecond*20 {
+			t.Fatalf("expected the second delay to follow the schedule, got %v", d)
+		}
+		c.Reset()
+		if d := c.Next(); d != time.Millisecond*10 {
+			t.Fatalf("expected no spread after a reset, got %v", d)
+		}
+	}
+	if len(offsets) < 2 {
+		t.Fatal("expected the first delays of clones to be spread out")
+	}
+
+	if _, err := New(WithStartupSpread(-1)); err == nil {
+		t.Fatal("expected an error for a negative startup spread")
+	}
+}
+
+func TestMaxDoublings(t *testing.T) {
+	t.Parallel()
+
+	ms := time.Millisecond
+	tests := map[string]struct {
+		options []backoffOption
+		want    []time.Duration
+	}{
+		"doublings only": {
+			[]backoffOption{WithMaxDoublings(2)},
+			[]time.Duration{ms * 100, ms * 200, ms * 400, ms * 400},
+		},
+		"limit only": {
+			[]backoffOption{WithExponentialLimit(ms * 200)},
+			[]time.Duration{ms * 100, ms * 200, ms * 200, ms * 200},
+		},
+		"limit first": {
+			[]backoffOption{WithMaxDoublings(3), WithExponentialLimit(ms * 200)},
+			[]time.Duration{ms * 100, ms * 200, ms * 200, ms * 200},
+		},
+		"doublings first": {
+			[]backoffOption{WithMaxDoublings(1), WithExponentialLimit(ms * 800)},
+			[]time.Duration{ms * 100, ms * 200, ms * 200, ms * 200},
+		},
+		"base step does not count": {
+			[]backoffOption{WithInitialDelay(0), WithMaxDoublings(1)},
+			[]time.Duration{0, ms * 100, ms * 200, ms * 200},
+		},
+	}
+	for name, tc := range tests {
+		b, err := New(tc.options...)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if got := b.schedule(len(tc.want)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+		}
+	}
+
+	b := CoerceNew(WithMaxDoublings(1))
+	b.Next()
+	b.Next()
+	if b.GrowthStalled() {
+		t.Fatal("expected the max doublings not to count as stalled growth")
+	}
+	if _, err := New(WithMaxDoublings(-1)); err == nil {
+		t.Fatal("expected an error for negative max doublings")
+	}
+}
+
+func TestDecrease(t *testing.T) {
+	t.Parallel()
+
+	ms := time.Millisecond
+	tests := map[string]struct {
+		growth GrowthStrategy
+		want   []time.Duration
+	}{
+		"exponential": {GrowthExponential, []time.Duration{ms * 1600, ms * 800, ms * 400, ms * 200, ms * 100}},
+		"fibonacci":   {GrowthFibonacci, []time.Duration{ms * 500, ms * 300, ms * 200, ms * 100, ms * 100}},
+		"linear":      {GrowthLinear, []time.Duration{ms * 500, ms * 400, ms * 300, ms * 200, ms * 100}},
+	}
+	for name, tc := range tests {
+		b := CoerceNew(WithGrowth(tc.growth), WithJitterFactor(0), WithMaxAttempts(4))
+		for i := 0; i < 4; i++ {
+			b.Next()
+		}
+		var got []time.Duration
+		for range tc.want {
+			got = append(got, b.PeekDelay())
+			b.Decrease()
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+		}
+		if b.Attempts() != 4 || !b.Done() {
+			t.Errorf("%s: expected the attempt count to be unchanged", name)
+		}
+	}
+
+	// it grows again from the decreased delay
+	b := CoerceNew(WithJitterFactor(0))
+	b.Next()
+	b.Next()
+	b.Decrease()
+	if d := b.Next(); d != ms*200 {
+		t.Fatalf("expected growth to resume from the decreased delay, got %v", d)
+	}
+
+	// the initial delay below the base delay is not raised
+	small := CoerceNew(WithInitialDelay(ms), WithJitterFactor(0))
+	small.Decrease()
+	if d := small.PeekDelay(); d != ms {
+		t.Fatalf("expected the delay to be unchanged, got %v", d)
+	}
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	b := CoerceNew(WithInitialDelay(time.Millisecond), WithJitterFactor(0))
+	if s := b.Stats(); s != (Stats{}) {
+		t.Fatalf("expected empty stats, got %+v", s)
+	}
+
+	b.Next()
+	b.Sleep()
+	b.NextDelay()
+	b.Advance()
+	want := Stats{Attempts: 4, TotalDelay: time.Millisecond * 15, LastDelay: time.Millisecond * 8}
+	if s := b.Stats(); s != want {
+		t.Fatalf("got %+v, want %+v", s, want)
+	}
+
+	b.Reset()
+	if s := b.Stats(); s != (Stats{}) {
+		t.Fatalf("expected Reset to zero the stats, got %+v", s)
+	}
+}
+
+func TestSoftLimit(t *testing.T) {
+	t.Parallel()
+
+	ms := time.Millisecond
+	b, err := New(WithExponentialLimit(ms*400), WithMaxDelay(ms*750), WithSoftLimit())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// exponential up to the limit, then linear by the base delay up to the max
+	want := []time.Duration{ms * 100, ms * 200, ms * 400, ms * 500, ms * 600, ms * 700, ms * 750, ms * 750}
+	if got := b.schedule(len(want)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := b.WorstCaseDelay(100); got != ms*750 {
+		t.Fatalf("expected the plateau at the max delay, got %v", got)
+	}
+
+	if _, err := New(WithSoftLimit()); err == nil {
+		t.Fatal("expected an error for a soft limit without a max delay")
+	}
+	if c := CoerceNew(WithSoftLimit()); c.softLimit {
+		t.Fatal("expected a soft limit without a max delay to be coerced to a hard limit")
+	}
+}
+
+func TestWithSchedule(t *testing.T) {
+	t.Parallel()
+
+	ms := time.Millisecond
+	b, err := New(
+		WithSchedule(ms*5, 0, ms*300),
+		WithInitialDelay(ms*10),
+		WithMaxDelay(ms*100),
+		WithJitterFactor(0.5),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// scripted delays are exact, ignoring jitter and the max delay
+	for i, want := range []time.Duration{ms * 5, 0, ms * 300} {
+		if d := b.Next(); d != want {
+			t.Fatalf("round %d: expected the scripted delay %v, got %v", i, want, d)
+		}
+	}
+	// then the backoff starts from its initial delay
+	if lo, hi := b.PeekRange(); lo != time.Microsecond*7500 || hi != time.Microsecond*12500 {
+		t.Fatalf("expected the normal schedule to start, got range [%v, %v]", lo, hi)
+	}
+	if b.Attempts() != 3 {
+		t.Fatalf("expected the scripted rounds to count, got %d attempts", b.Attempts())
+	}
+	if got := b.Schedule(5); !reflect.DeepEqual(got, []time.Duration{ms * 5, 0, ms * 300, ms * 10, ms * 20}) {
+		t.Fatalf("expected Schedule to include the scripted delays, got %v", got)
+	}
+
+	b.Reset()
+	if d := b.Next(); d != ms*5 {
+		t.Fatalf("expected Reset to restart the script, got %v", d)
+	}
+
+	if _, err := New(WithSchedule(ms, -ms)); err == nil {
+		t.Fatal("expected an error for a negative scheduled delay")
+	}
+}
@@ -0,0 +1,28 @@
+package backoff
+
+import "time"
+
+// AsCenkalti returns an adapter implementing the BackOff interface of
+// github.com/cenkalti/backoff, to ease migration of code that depends on it.
+// NextBackOff behaves like NextDelay, returning Stop (which equals that
+// package's Stop value) once the limits are exhausted, and Reset restores the
+// backoff to its initial state. The adapter shares the state of the backoff,
+// rather than copying it.
+func (b *Backoff) AsCenkalti() interface {
+	NextBackOff() time.Duration
+	Reset()
+} {
+	return cenkaltiAdapter{b}
+}
+
+type cenkaltiAdapter struct {
+	b *Backoff
+}
+
+func (a cenkaltiAdapter) NextBackOff() time.Duration {
+	return a.b.NextDelay()
+}
+
+func (a cenkaltiAdapter) Reset() {
+	a.b.Reset()
+}
@@ -0,0 +1,29 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAsCenkalti(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Second), WithJitterFactor(0), WithMaxAttempts(3))
+	a := b.AsCenkalti()
+	for _, want := range []time.Duration{time.Second, time.Second * 2, time.Second * 4} {
+		if d := a.NextBackOff(); d != want {
+			t.Fatalf("got: %v, want: %v", d, want)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if d := a.NextBackOff(); d != Stop {
+			t.Fatalf("expected the stop sentinel once exhausted, got %v", d)
+		}
+	}
+
+	a.Reset()
+	if d := a.NextBackOff(); d != time.Second {
+		t.Fatalf("expected the initial delay after a reset, got %v", d)
+	}
+	if b.attempts != 1 {
+		t.Fatalf("expected the adapter to share the backoff's state")
+	}
+}
@@ -0,0 +1,50 @@
+package backoff
+
+import "time"
+
+// Clock is the source of time for a backoff: for the max elapsed time, global
+// slots and history, and for sleeping. It can be replaced with WithClock, e.g.
+// to test timing behavior without real sleeps.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer returns a timer that sends the current time on its channel
+	// once d has elapsed, like time.NewTimer.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-use timer created by a Clock, like time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is sent when the timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, like time.Timer's Stop.
+	Stop() bool
+}
+
+// realClock is the Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// sleep pauses for d, as measured by the clock.
+func sleep(c Clock, d time.Duration) {
+	<-c.NewTimer(d).C()
+}
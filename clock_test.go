@@ -0,0 +1,94 @@
+package backoff
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// nowClock is a Clock that reads the time from a function, with real timers.
+type nowClock func() time.Time
+
+func (c nowClock) Now() time.Time {
+	return c()
+}
+
+func (c nowClock) NewTimer(d time.Duration) Timer {
+	return realClock{}.NewTimer(d)
+}
+
+// fakeClock is a Clock whose timers fire immediately, advancing its time by
+// their duration, so sleeps take no real time.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.slept = append(c.slept, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return fakeTimer{ch}
+}
+
+type fakeTimer struct {
+	ch chan time.Time
+}
+
+func (t fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t fakeTimer) Stop() bool {
+	return false
+}
+
+func TestWithClock(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	b := CoerceNew(
+		WithInitialDelay(time.Minute),
+		WithJitterFactor(0),
+		WithMaxElapsed(time.Minute*10),
+		WithClock(clock),
+	)
+
+	start := time.Now()
+	b.Sleep()
+	if err := b.SleepContext(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-b.After()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected no real sleeps, took %v", elapsed)
+	}
+	if want := []time.Duration{time.Minute, time.Minute * 2, time.Minute * 4}; !reflect.DeepEqual(clock.slept, want) {
+		t.Fatalf("expected sleeps of %v, got %v", want, clock.slept)
+	}
+
+	// 7 minutes have passed on the clock, since the first round
+	if b.Done() {
+		t.Fatalf("expected the backoff not to be done within the max elapsed time")
+	}
+	b.Sleep()
+	if !b.Done() {
+		t.Fatalf("expected the backoff to be done once the clock passed the max elapsed time")
+	}
+
+	if _, err := New(WithClock(nil)); err == nil {
+		t.Fatalf("expected an error for a nil clock")
+	}
+}
@@ -0,0 +1,34 @@
+package backoff
+
+import "time"
+
+// Config is a plain configuration of the core settings of a backoff, for
+// callers that load configuration from files or the environment into a single
+// struct. Fields left at their zero values take the defaults documented for
+// the corresponding options, so a zero value cannot be used to disable jitter,
+// or to retry immediately at first; use New with options for those.
+type Config struct {
+	InitialDelay     time.Duration // see WithInitialDelay
+	BaseDelay        time.Duration // see WithBaseDelay
+	ExponentialLimit time.Duration // see WithExponentialLimit
+	JitterFactor     float64       // see WithJitterFactor
+}
+
+// NewFromConfig creates a new exponential backoff object from the config,
+// validating it as New does, so an invalid field returns an error.
+func NewFromConfig(c Config) (*Backoff, error) {
+	var options []backoffOption
+	if c.InitialDelay != 0 {
+		options = append(options, WithInitialDelay(c.InitialDelay))
+	}
+	if c.BaseDelay != 0 {
+		options = append(options, WithBaseDelay(c.BaseDelay))
+	}
+	if c.ExponentialLimit != 0 {
+		options = append(options, WithExponentialLimit(c.ExponentialLimit))
+	}
+	if c.JitterFactor != 0 {
+		options = append(options, WithJitterFactor(c.JitterFactor))
+	}
+	return New(options...)
+}
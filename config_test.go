@@ -0,0 +1,42 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	tests := map[string]struct {
+		c       Config
+		want    string
+		wantErr bool
+	}{
+		"zero value takes the defaults": {
+			Config{},
+			"Backoff{initial=100ms, base=100ms, expLimit=3m0s, jitter=0.30}",
+			false,
+		},
+		"all fields": {
+			Config{time.Second, time.Millisecond * 500, time.Minute, 0.1},
+			"Backoff{initial=1s, base=500ms, expLimit=1m0s, jitter=0.10}",
+			false,
+		},
+		"some fields": {
+			Config{ExponentialLimit: time.Minute},
+			"Backoff{initial=100ms, base=100ms, expLimit=1m0s, jitter=0.30}",
+			false,
+		},
+		"negative delay": {Config{InitialDelay: -1}, "", true},
+		"invalid jitter": {Config{JitterFactor: 1.5}, "", true},
+		"negative limit": {Config{ExponentialLimit: -time.Second}, "", true},
+	}
+	for name, tc := range tests {
+		b, err := NewFromConfig(tc.c)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if err == nil && b.String() != tc.want {
+			t.Fatalf("%s: got: %v, want: %v", name, b, tc.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package backoff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GrowthStrategy identifies how the backoff delay grows in each round.
+type GrowthStrategy int
+
+const (
+	// GrowthExponential multiplies the delay by the multiplier in each round.
+	GrowthExponential GrowthStrategy = iota
+	// GrowthFibonacci adds the previous delay to the delay in each round, so
+	// that it grows as 1, 1, 2, 3, 5, 8, ... times the initial delay (or the
+	// base delay, if the initial delay is 0).
+	GrowthFibonacci
+	// GrowthLinear adds the base delay to the delay in each round, so that,
+	// starting from the base delay, it grows as 1, 2, 3, 4, ... times the base
+	// delay. This is gentler, and suits failures that are expected to clear
+	// quickly.
+	GrowthLinear
+)
+
+var growthStrategyNames = map[GrowthStrategy]string{
+	GrowthExponential: "exponential",
+	GrowthFibonacci:   "fibonacci",
+	GrowthLinear:      "linear",
+}
+
+// String returns the name of the growth strategy, as accepted by
+// ParseGrowthStrategy.
+func (s GrowthStrategy) String() string {
+	if name, ok := growthStrategyNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("GrowthStrategy(%d)", int(s))
+}
+
+// ParseGrowthStrategy returns the growth strategy with the given name,
+// ignoring case and surrounding whitespace, e.g. for selecting the strategy
+// from a configuration file.
+func ParseGrowthStrategy(name string) (GrowthStrategy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for s, n := range growthStrategyNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown growth strategy: %q", name)
+}
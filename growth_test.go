@@ -0,0 +1,82 @@
+package backoff
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGrowthStrategyText(t *testing.T) {
+	for s := range growthStrategyNames {
+		got, err := ParseGrowthStrategy(s.String())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != s {
+			t.Fatalf("round trip of %v, got: %v", s, got)
+		}
+	}
+
+	if s, err := ParseGrowthStrategy(" Fibonacci "); err != nil || s != GrowthFibonacci {
+		t.Fatalf("expected %v, got: %v, err: %v", GrowthFibonacci, s, err)
+	}
+	if _, err := ParseGrowthStrategy("bogus"); err == nil {
+		t.Fatalf("expected error for unknown strategy")
+	}
+	if got := GrowthStrategy(-1).String(); got != "GrowthStrategy(-1)" {
+		t.Fatalf("unexpected name for invalid strategy: %v", got)
+	}
+}
+
+func TestGrowth(t *testing.T) {
+	tests := map[string]struct {
+		options []backoffOption
+		want    []time.Duration
+	}{
+		"exponential by default": {
+			nil,
+			[]time.Duration{1, 2, 4, 8, 16, 16},
+		},
+		"fibonacci": {
+			[]backoffOption{WithGrowth(GrowthFibonacci)},
+			[]time.Duration{1, 1, 2, 3, 5, 8, 13, 13},
+		},
+		"fibonacci from the base delay": {
+			[]backoffOption{WithGrowth(GrowthFibonacci), WithInitialDelay(0), WithBaseDelay(2)},
+			[]time.Duration{0, 2, 2, 4, 6, 10, 10},
+		},
+		"linear": {
+			[]backoffOption{WithGrowth(GrowthLinear), WithBaseDelay(1)},
+			[]time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10},
+		},
+		"linear from a larger initial delay": {
+			[]backoffOption{WithGrowth(GrowthLinear), WithInitialDelay(4), WithBaseDelay(3)},
+			[]time.Duration{4, 7, 10, 10},
+		},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			options := append([]backoffOption{
+				WithInitialDelay(1),
+				WithExponentialLimit(10),
+				WithJitterFactor(0),
+			}, tc.options...)
+			b, err := New(options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.schedule(len(tc.want)); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := New(WithGrowth(GrowthStrategy(-1))); err == nil {
+		t.Fatalf("expected an error for an unknown growth strategy")
+	}
+	if b := CoerceNew(WithGrowth(GrowthStrategy(-1))); b.growth != GrowthExponential {
+		t.Fatalf("expected an unknown growth strategy to be coerced to the default")
+	}
+}
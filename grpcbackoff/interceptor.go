@@ -0,0 +1,36 @@
+// Package grpcbackoff retries gRPC calls using a backoff. It is a separate
+// module, so that the backoff package itself has no gRPC dependency.
+package grpcbackoff
+
+import (
+	"context"
+
+	"github.com/bitdabbler/backoff"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns an interceptor that retries unary calls that
+// fail with any of the retryable status codes, pausing between attempts using
+// a per-call clone of the backoff (in its initial state). The pauses are
+// interrupted by the call's context, so its deadline is respected, in which
+// case the last error is returned. Calls failing with any other code are not
+// retried. If no codes are given, only codes.Unavailable is retried.
+func UnaryClientInterceptor(b *backoff.Backoff, retryableCodes ...codes.Code) grpc.UnaryClientInterceptor {
+	if len(retryableCodes) == 0 {
+		retryableCodes = []codes.Code{codes.Unavailable}
+	}
+	retryable := make(map[codes.Code]bool, len(retryableCodes))
+	for _, c := range retryableCodes {
+		retryable[c] = true
+	}
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return b.Clone().Do(ctx, func() error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}, func(err error) bool {
+			return retryable[status.Code(err)]
+		})
+	}
+}
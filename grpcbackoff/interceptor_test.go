@@ -0,0 +1,66 @@
+package grpcbackoff
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitdabbler/backoff"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	tests := map[string]struct {
+		codes     []codes.Code
+		errs      []codes.Code
+		wantCode  codes.Code
+		wantCalls int
+	}{
+		"retryable codes are retried":       {[]codes.Code{codes.Unavailable, codes.ResourceExhausted}, []codes.Code{codes.Unavailable, codes.ResourceExhausted}, codes.OK, 3},
+		"other codes are returned at once":  {[]codes.Code{codes.Unavailable}, []codes.Code{codes.Unavailable, codes.InvalidArgument}, codes.InvalidArgument, 2},
+		"unavailable is retried by default": {nil, []codes.Code{codes.Unavailable}, codes.OK, 2},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			b := backoff.CoerceNew(backoff.WithInitialDelay(time.Millisecond), backoff.WithJitterFactor(0))
+			calls := 0
+			invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				calls++
+				if calls <= len(tc.errs) {
+					return status.Error(tc.errs[calls-1], "failed")
+				}
+				return nil
+			}
+			err := UnaryClientInterceptor(b, tc.codes...)(context.Background(), "/svc/Method", nil, nil, nil, invoker)
+			if got := status.Code(err); got != tc.wantCode {
+				t.Errorf("got code %v, want %v", got, tc.wantCode)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tc.wantCalls)
+			}
+			if b.Attempts() != 0 {
+				t.Errorf("the interceptor advanced the template backoff")
+			}
+		})
+	}
+}
+
+func TestUnaryClientInterceptorDeadline(t *testing.T) {
+	b := backoff.CoerceNew(backoff.WithInitialDelay(time.Hour), backoff.WithJitterFactor(0))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	calls := 0
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		return status.Error(codes.Unavailable, "down")
+	}
+	err := UnaryClientInterceptor(b)(ctx, "/svc/Method", nil, nil, nil, invoker)
+	if status.Code(err) != codes.Unavailable || calls != 1 {
+		t.Fatalf("got %v after %d calls, want the last error after 1 call", err, calls)
+	}
+}
@@ -0,0 +1,84 @@
+package backoff
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Event describes a single round of a backoff, as retained by WithHistory.
+type Event struct {
+	Time    time.Time     // when the delay was computed
+	Delay   time.Duration // the delay, after jitter
+	AtLimit bool          // whether the backoff had stopped growing
+}
+
+// WithHistory configuration BackoffOption makes the backoff retain its most
+// recent rounds, up to the given number, so they can be inspected with
+// History after an incident. The size must be >= 0, and the default is 0,
+// meaning that no history is retained (which costs nothing).
+func WithHistory(size int) backoffOption {
+	return func(b *Backoff, coerce bool) error {
+		if size >= 0 {
+			b.history = newHistory(size)
+			return nil
+		}
+		if !coerce {
+			return errors.New("the history size must be >= 0")
+		}
+		// assume caller wanted no history
+		b.history = nil
+		return nil
+	}
+}
+
+// History returns a copy of the most recent rounds of the backoff, oldest
+// first, if WithHistory was used.
+func (b *Backoff) History() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.history == nil {
+		return nil
+	}
+	return b.history.events()
+}
+
+// history is a ring buffer of events, safe for concurrent use.
+type history struct {
+	mu   sync.Mutex
+	buf  []Event
+	next int
+	full bool
+}
+
+func newHistory(size int) *history {
+	if size == 0 {
+		return nil
+	}
+	return &history{buf: make([]Event, size)}
+}
+
+func (h *history) add(e Event) {
+	h.mu.Lock()
+	h.buf[h.next] = e
+	h.next = (h.next + 1) % len(h.buf)
+	h.full = h.full || h.next == 0
+	h.mu.Unlock()
+}
+
+// clear discards the events, keeping the buffer.
+func (h *history) clear() {
+	h.mu.Lock()
+	clear(h.buf)
+	h.next, h.full = 0, false
+	h.mu.Unlock()
+}
+
+func (h *history) events() []Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.full {
+		return append([]Event(nil), h.buf[:h.next]...)
+	}
+	return append(append([]Event(nil), h.buf[h.next:]...), h.buf[:h.next]...)
+}
@@ -0,0 +1,93 @@
+package backoff
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	const size = 3
+	b := CoerceNew(
+		WithInitialDelay(1),
+		WithExponentialLimit(8),
+		WithJitterFactor(0),
+		WithHistory(size),
+	)
+	if h := b.History(); len(h) != 0 {
+		t.Fatalf("expected empty history, got %v", h)
+	}
+
+	b.computeDelay()
+	b.computeDelay()
+	if h := b.History(); len(h) != 2 || h[0].Delay != 1 || h[1].Delay != 2 {
+		t.Fatalf("unexpected history: %v", h)
+	}
+
+	for i := 0; i < 4; i++ {
+		b.computeDelay()
+	}
+	h := b.History()
+	if len(h) != size {
+		t.Fatalf("expected history capped at %d, got %d", size, len(h))
+	}
+	// rounds 4, 5, and 6: 8, 8, 8
+	for i, e := range h {
+		if e.Delay != 8 || !e.AtLimit {
+			t.Fatalf("event %d: unexpected %+v", i, e)
+		}
+		if i > 0 && e.Time.Before(h[i-1].Time) {
+			t.Fatalf("history out of order: %v", h)
+		}
+	}
+
+	// concurrent rounds through clones, each with its own history
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(c *Backoff) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.computeDelay()
+				c.History()
+			}
+		}(b.clone())
+	}
+	wg.Wait()
+	if len(b.clone().History()) != 0 {
+		t.Fatalf("expected clones to start with an empty history")
+	}
+
+	// concurrent rounds and reads of a shared history
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b.Next()
+				b.History()
+			}
+		}()
+	}
+	wg.Wait()
+
+	h = b.History()
+	h[0].Delay = -1
+	if b.History()[0].Delay == -1 {
+		t.Fatalf("expected History to return a copy")
+	}
+
+	if CoerceNew().History() != nil {
+		t.Fatalf("expected no history by default")
+	}
+}
+
+func TestHistoryTime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := CoerceNew(WithHistory(1))
+	b.clock = nowClock(func() time.Time { return now })
+	b.computeDelay()
+	if h := b.History(); !h[0].Time.Equal(now) {
+		t.Fatalf("got: %v, want: %v", h[0].Time, now)
+	}
+}
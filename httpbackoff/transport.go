@@ -0,0 +1,139 @@
+// Package httpbackoff retries HTTP requests using a backoff.
+package httpbackoff
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bitdabbler/backoff"
+)
+
+// maxDrain is the most of a discarded response body that is read, so that its
+// connection can be reused, before it is closed.
+const maxDrain = 4 << 10
+
+type retryTransport struct {
+	base http.RoundTripper
+	b    *backoff.Backoff
+}
+
+// NewRetryTransport returns a transport that retries idempotent requests made
+// with base (http.DefaultTransport, if nil), pausing between attempts using a
+// per-request clone of the backoff (in its initial state). Requests are
+// retried after connection errors, and after 5xx and 429 responses, in which
+// case a Retry-After header, when present, is honored instead of the backoff
+// delay (see Backoff.Override). The pauses are interrupted by the request's
+// context. Once the max attempts of the backoff are exhausted, the last
+// response or error is returned.
+//
+// Requests are idempotent if their method is, or if they have an
+// Idempotency-Key or X-Idempotency-Key header, as for http.Transport. Other
+// requests are made once. A request body is buffered, unless the request has
+// GetBody, so that it can be replayed.
+func NewRetryTransport(base http.RoundTripper, b *backoff.Backoff) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, b: b}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isIdempotent(req) {
+		return t.base.RoundTrip(req)
+	}
+	getBody, err := rewindable(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := req.Context()
+	b := t.b.Clone()
+	for {
+		r := req.Clone(ctx)
+		if getBody != nil {
+			if r.Body, err = getBody(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := t.base.RoundTrip(r)
+		if !shouldRetry(resp, err) || ctx.Err() != nil || b.Done() {
+			return resp, err
+		}
+
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				b.Override(d)
+			}
+			drain(resp.Body)
+		}
+		if err := b.SleepContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isIdempotent reports whether the request can safely be retried.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := req.Header["Idempotency-Key"]
+	if !ok {
+		_, ok = req.Header["X-Idempotency-Key"]
+	}
+	return ok
+}
+
+// rewindable returns a function that returns a new copy of the request body,
+// buffering the body if need be, or nil if the request has no body. The
+// original body is always closed.
+func rewindable(req *http.Request) (func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	if req.GetBody != nil {
+		return req.GetBody, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}, nil
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth retrying.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryAfter parses a Retry-After header value, given either as a number of
+// seconds or as an HTTP date, into the delay from now.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(min(max(s, 0), math.MaxInt64/int64(time.Second))) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// drain discards the rest of a response body, up to a limit, and closes it.
+func drain(body io.ReadCloser) {
+	_, _ = io.CopyN(io.Discard, body, maxDrain)
+	body.Close()
+}
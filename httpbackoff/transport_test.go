@@ -0,0 +1,127 @@
+package httpbackoff
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitdabbler/backoff"
+)
+
+func TestRetryTransport(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		header     string
+		statuses   []int
+		wantStatus int
+		wantCalls  int
+	}{
+		"5xx is retried":                {http.MethodGet, "", []int{500, 503}, 200, 3},
+		"429 is retried":                {http.MethodPut, "", []int{429}, 200, 2},
+		"4xx is not retried":            {http.MethodGet, "", []int{404}, 404, 1},
+		"post is not retried":           {http.MethodPost, "", []int{503}, 503, 1},
+		"post with key is retried":      {http.MethodPost, "Idempotency-Key", []int{503}, 200, 2},
+		"last response after max tries": {http.MethodGet, "", []int{503, 503, 503, 503}, 503, 3},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != "payload" {
+					t.Errorf("attempt %d got body %q", calls, body)
+				}
+				calls++
+				if calls <= len(tc.statuses) {
+					w.WriteHeader(tc.statuses[calls-1])
+				}
+			}))
+			defer srv.Close()
+
+			b := backoff.CoerceNew(
+				backoff.WithInitialDelay(time.Millisecond),
+				backoff.WithJitterFactor(0),
+				backoff.WithMaxAttempts(2),
+			)
+			client := &http.Client{Transport: NewRetryTransport(nil, b)}
+			req, _ := http.NewRequest(tc.method, srv.URL, io.NopCloser(strings.NewReader("payload")))
+			if tc.header != "" {
+				req.Header.Set(tc.header, "key")
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tc.wantStatus)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryTransportRetryAfter(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	b := backoff.CoerceNew(backoff.WithInitialDelay(time.Hour), backoff.WithJitterFactor(0))
+	client := &http.Client{Transport: NewRetryTransport(nil, b)}
+
+	// Retry-After is honored instead of the hour-long backoff delay
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); resp.StatusCode != http.StatusOK || elapsed < time.Second || elapsed > time.Second*10 {
+		t.Fatalf("got status %d after %v, want 200 after 1s", resp.StatusCode, elapsed)
+	}
+
+	// the pause is interrupted by the request's context
+	calls = 0
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("expected the context error")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		"missing":     {"", 0, false},
+		"seconds":     {"120", time.Minute * 2, true},
+		"negative":    {"-5", 0, true},
+		"http date":   {"Mon, 01 Jan 2024 00:00:30 GMT", time.Second * 30, true},
+		"past date":   {"Sun, 31 Dec 2023 23:00:00 GMT", 0, true},
+		"unparseable": {"soon", 0, false},
+	}
+	for name, tc := range tests {
+		got, ok := retryAfter(tc.value, now)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("%s: got (%v, %v), want (%v, %v)", name, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
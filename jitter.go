@@ -0,0 +1,64 @@
+package backoff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JitterStrategy identifies how jitter is applied to the backoff delay.
+type JitterStrategy int
+
+const (
+	// JitterSymmetric spreads the delay uniformly about its nominal value,
+	// across a band that is a fraction (the jitter factor) of the delay.
+	JitterSymmetric JitterStrategy = iota
+	// JitterFull spreads the delay uniformly across [0, delay], ignoring the
+	// jitter factor ("full jitter", as described in the AWS Architecture Blog).
+	// This minimizes contention between many clients, at the cost of
+	// sometimes retrying almost immediately.
+	JitterFull
+	// JitterDecorrelated picks each delay uniformly between the base delay and
+	// three times the previous delay, capped at the exponential limit
+	// ("decorrelated jitter", as described in the AWS Architecture Blog). The
+	// delays grow randomly rather than following the exponential schedule,
+	// starting as if the previous delay was the base delay, so the initial
+	// delay and the jitter factor are not used.
+	JitterDecorrelated
+	// JitterEqual spreads the delay uniformly across [delay/2, delay] ("equal
+	// jitter"), ignoring the jitter factor. It guarantees at least half of the
+	// delay, while still spreading the load.
+	JitterEqual
+)
+
+// JitterProportional is another name for JitterSymmetric, whose band is
+// proportional to the delay.
+const JitterProportional = JitterSymmetric
+
+var jitterStrategyNames = map[JitterStrategy]string{
+	JitterSymmetric:    "symmetric",
+	JitterFull:         "full",
+	JitterDecorrelated: "decorrelated",
+	JitterEqual:        "equal",
+}
+
+// String returns the name of the jitter strategy, as accepted by
+// ParseJitterStrategy.
+func (s JitterStrategy) String() string {
+	if name, ok := jitterStrategyNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("JitterStrategy(%d)", int(s))
+}
+
+// ParseJitterStrategy returns the jitter strategy with the given name, ignoring
+// case and surrounding whitespace, e.g. for selecting the strategy from a
+// configuration file.
+func ParseJitterStrategy(name string) (JitterStrategy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for s, n := range jitterStrategyNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown jitter strategy: %q", name)
+}
@@ -0,0 +1,25 @@
+package backoff
+
+import "testing"
+
+func TestJitterStrategyText(t *testing.T) {
+	for s := range jitterStrategyNames {
+		got, err := ParseJitterStrategy(s.String())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != s {
+			t.Fatalf("round trip of %v, got: %v", s, got)
+		}
+	}
+
+	if s, err := ParseJitterStrategy(" Symmetric "); err != nil || s != JitterSymmetric {
+		t.Fatalf("expected %v, got: %v, err: %v", JitterSymmetric, s, err)
+	}
+	if _, err := ParseJitterStrategy("bogus"); err == nil {
+		t.Fatalf("expected error for unknown strategy")
+	}
+	if got := JitterStrategy(-1).String(); got != "JitterStrategy(-1)" {
+		t.Fatalf("unexpected name for invalid strategy: %v", got)
+	}
+}
@@ -0,0 +1,279 @@
+package backoff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// backoffJSON is the JSON form of a Backoff's configuration. Durations are
+// strings in the format of time.ParseDuration, e.g. "1m30s".
+type backoffJSON struct {
+	Name            string   `json:"name,omitempty"`
+	InitialDelay    string   `json:"initialDelay"`
+	BaseDelay       string   `json:"baseDelay"`
+	ExpLimit        string   `json:"expLimit"`
+	JitterFactor    *float64 `json:"jitterFactor"`
+	Multiplier      float64  `json:"multiplier,omitempty"`
+	Growth          string   `json:"growth,omitempty"`
+	MaxDoublings    int      `json:"maxDoublings,omitempty"`
+	JitterMode      string   `json:"jitterMode,omitempty"`
+	AbsoluteJitter  string   `json:"absoluteJitter,omitempty"`
+	JitterLow       *float64 `json:"jitterLow,omitempty"`
+	JitterHigh      *float64 `json:"jitterHigh,omitempty"`
+	MaxJitter       string   `json:"maxJitter,omitempty"`
+	MinDelay        string   `json:"minDelay,omitempty"`
+	MaxDelay        string   `json:"maxDelay,omitempty"`
+	MaxAttempts     int      `json:"maxAttempts,omitempty"`
+	MaxElapsed      string   `json:"maxElapsed,omitempty"`
+	LimitAsFloor    bool     `json:"limitAsFloor,omitempty"`
+	SoftLimit       bool     `json:"softLimit,omitempty"`
+	BaseDelayAlways bool     `json:"baseDelayAlways,omitempty"`
+	FallbackProb    float64  `json:"fallbackProb,omitempty"`
+	FallbackDelay   string   `json:"fallbackDelay,omitempty"`
+
+	StartupSpread     string   `json:"startupSpread,omitempty"`
+	Schedule          []string `json:"schedule,omitempty"`
+	Replay            []string `json:"replay,omitempty"`
+	PauseAfter        int      `json:"pauseAfter,omitempty"`
+	PauseDelay        string   `json:"pauseDelay,omitempty"`
+	Slot              string   `json:"slot,omitempty"`
+	SlotOffset        string   `json:"slotOffset,omitempty"`
+	OnExhausted       string   `json:"onExhausted,omitempty"`
+	LatencyMultiplier float64  `json:"latencyMultiplier,omitempty"`
+	AdvanceOnCancel   bool     `json:"advanceOnCancel,omitempty"`
+	HistorySize       int      `json:"historySize,omitempty"`
+}
+
+// MarshalJSON encodes the configuration of the backoff, with durations as
+// human-friendly strings, e.g. {"initialDelay":"100ms",...}. Settings that
+// cannot be represented in JSON are not included: the callbacks (see
+// WithDelayOverride and WithOnRetry), the recorder, the clock and the random
+// source. Nor is the state of the backoff: its current progress (see
+// Snapshot), the events in its history, or the average latency observed by
+// ObserveLatency, though the base delay derived from it is included.
+func (b *Backoff) MarshalJSON() ([]byte, error) {
+	b.mu.Lock()
+	j := b.config()
+	b.mu.Unlock()
+	return json.Marshal(j)
+}
+
+// config returns the configuration of the backoff in its encodable form. The
+// caller must hold the lock.
+func (b *Backoff) config() backoffJSON {
+	optional := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.String()
+	}
+	jitterFactor := b.jitterFactor
+	j := backoffJSON{
+		Name:            b.name,
+		InitialDelay:    b.initDelay.String(),
+		BaseDelay:       b.baseDelay.String(),
+		ExpLimit:        b.expLimit.String(),
+		JitterFactor:    &jitterFactor,
+		AbsoluteJitter:  optional(b.absJitter),
+		MaxJitter:       optional(b.maxJitter),
+		MinDelay:        optional(b.minDelay),
+		MaxDelay:        optional(b.maxDelay),
+		MaxDoublings:    b.maxDoublings,
+		MaxAttempts:     b.maxAttempts,
+		MaxElapsed:      optional(b.maxElapsed),
+		LimitAsFloor:    b.limitAsFloor,
+		SoftLimit:       b.softLimit,
+		BaseDelayAlways: b.baseAlways,
+		FallbackProb:    b.fallbackProb,
+	}
+	if b.multiplier != defaultMultiplier {
+		j.Multiplier = b.multiplier
+	}
+	if b.growth != GrowthExponential {
+		j.Growth = b.growth.String()
+	}
+	if b.jitterMode != JitterSymmetric {
+		j.JitterMode = b.jitterMode.String()
+	}
+	if b.jitterBounds {
+		low, high := b.jitterLow, b.jitterHigh
+		j.JitterLow, j.JitterHigh = &low, &high
+	}
+	if b.fallbackProb > 0 {
+		j.FallbackDelay = b.fallbackDelay.String()
+	}
+	j.StartupSpread = optional(b.startupSpread)
+	j.Schedule = durationStrings(b.script)
+	j.Replay = durationStrings(b.replay)
+	if b.pauseAfter > 0 {
+		j.PauseAfter, j.PauseDelay = b.pauseAfter, b.pauseDelay.String()
+	}
+	if b.slot > 0 {
+		j.Slot, j.SlotOffset = b.slot.String(), optional(b.slotOffset)
+	}
+	if b.onExhausted != ExhaustedNoop {
+		j.OnExhausted = b.onExhausted.String()
+	}
+	if b.latencyMultiplier != defaultLatencyMultiplier {
+		j.LatencyMultiplier = b.latencyMultiplier
+	}
+	j.AdvanceOnCancel = b.advanceOnCancel
+	if b.history != nil {
+		j.HistorySize = len(b.history.buf)
+	}
+	return j
+}
+
+// durationStrings returns the durations in the format of time.Duration.String,
+// or nil if there are none.
+func durationStrings(ds []time.Duration) []string {
+	if len(ds) == 0 {
+		return nil
+	}
+	ss := make([]string, len(ds))
+	for i, d := range ds {
+		ss[i] = d.String()
+	}
+	return ss
+}
+
+// UnmarshalJSON replaces the configuration of the backoff with the one
+// encoded by MarshalJSON, and restores it to its initial state. Settings that
+// are missing, or that cannot be represented in JSON, take their default
+// values. The settings are validated as by New, so an invalid configuration
+// returns an error, leaving the backoff unchanged.
+func (b *Backoff) UnmarshalJSON(data []byte) error {
+	var j backoffJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	return b.setConfig(j)
+}
+
+// setConfig replaces the configuration of the backoff with the encodable form
+// j, validated as by New, and restores it to its initial state.
+func (b *Backoff) setConfig(j backoffJSON) error {
+	var errs error
+	duration := func(name, s string) time.Duration {
+		if s == "" {
+			return 0
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("invalid %s: %w", name, err))
+		}
+		return d
+	}
+	durations := func(name string, ss []string) []time.Duration {
+		var ds []time.Duration
+		for _, s := range ss {
+			ds = append(ds, duration(name, s))
+		}
+		return ds
+	}
+	options := []backoffOption{
+		WithName(j.Name),
+		WithStartupSpread(duration("startupSpread", j.StartupSpread)),
+		WithAdvanceOnCancel(j.AdvanceOnCancel),
+		WithHistory(j.HistorySize),
+		WithMaxJitterAbsolute(duration("maxJitter", j.MaxJitter)),
+		WithMaxAttempts(j.MaxAttempts),
+		WithMaxDoublings(j.MaxDoublings),
+		WithMinDelay(duration("minDelay", j.MinDelay)),
+		WithMaxDelay(duration("maxDelay", j.MaxDelay)),
+		WithMaxElapsed(duration("maxElapsed", j.MaxElapsed)),
+	}
+	if j.InitialDelay != "" {
+		options = append(options, WithInitialDelay(duration("initialDelay", j.InitialDelay)))
+	}
+	if j.BaseDelay != "" {
+		options = append(options, WithBaseDelay(duration("baseDelay", j.BaseDelay)))
+	}
+	if j.ExpLimit != "" {
+		options = append(options, WithExponentialLimit(duration("expLimit", j.ExpLimit)))
+	}
+	if j.JitterFactor != nil {
+		options = append(options, WithJitterFactor(*j.JitterFactor))
+	}
+	if j.AbsoluteJitter != "" {
+		options = append(options, WithAbsoluteJitter(duration("absoluteJitter", j.AbsoluteJitter)))
+	}
+	if j.JitterLow != nil || j.JitterHigh != nil {
+		var low, high float64
+		if j.JitterLow != nil {
+			low = *j.JitterLow
+		}
+		if j.JitterHigh != nil {
+			high = *j.JitterHigh
+		}
+		options = append(options, WithJitterBounds(low, high))
+	}
+	if j.Multiplier != 0 {
+		options = append(options, WithMultiplier(j.Multiplier))
+	}
+	if j.Growth != "" {
+		growth, err := ParseGrowthStrategy(j.Growth)
+		errs = errors.Join(errs, err)
+		options = append(options, WithGrowth(growth))
+	}
+	if j.JitterMode != "" {
+		mode, err := ParseJitterStrategy(j.JitterMode)
+		errs = errors.Join(errs, err)
+		options = append(options, WithJitterMode(mode))
+	}
+	if j.LimitAsFloor {
+		options = append(options, WithLimitAsFloor())
+	}
+	if j.SoftLimit {
+		options = append(options, WithSoftLimit())
+	}
+	if j.BaseDelayAlways {
+		options = append(options, WithBaseDelayAlways())
+	}
+	if j.FallbackProb != 0 {
+		options = append(options, WithConstantFallback(j.FallbackProb, duration("fallbackDelay", j.FallbackDelay)))
+	}
+	if len(j.Schedule) > 0 {
+		options = append(options, WithSchedule(durations("schedule", j.Schedule)...))
+	}
+	if j.PauseAfter != 0 {
+		options = append(options, WithPlateauPause(j.PauseAfter, duration("pauseDelay", j.PauseDelay)))
+	}
+	if j.Slot != "" {
+		options = append(options, WithGlobalSlots(duration("slot", j.Slot), duration("slotOffset", j.SlotOffset)))
+	}
+	if j.OnExhausted != "" {
+		policy, err := ParseExhaustedPolicy(j.OnExhausted)
+		errs = errors.Join(errs, err)
+		options = append(options, WithOnExhausted(policy))
+	}
+	if j.LatencyMultiplier != 0 {
+		options = append(options, WithLatencyMultiplier(j.LatencyMultiplier))
+	}
+	replay := durations("replay", j.Replay)
+	for _, d := range replay {
+		if d < 0 {
+			errs = errors.Join(errs, errors.New("the replayed delays must be >= 0"))
+			break
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+
+	c, err := New(options...)
+	if err != nil {
+		return err
+	}
+	// replayed delays replace growth, as in ReplayBackoff, whose initial delay
+	// and jitter factor are encoded with the other settings
+	c.replay = replay
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.settings = c.settings
+	b.reset()
+	return nil
+}
@@ -0,0 +1,109 @@
+package backoff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := map[string]*Backoff{
+		"defaults": CoerceNew(),
+		"core settings": CoerceNew(
+			WithInitialDelay(0),
+			WithBaseDelay(time.Millisecond*250),
+			WithExponentialLimit(time.Minute*2),
+			WithJitterFactor(0),
+		),
+		"all settings": CoerceNew(
+			WithMultiplier(1.5),
+			WithGrowth(GrowthFibonacci),
+			WithJitterMode(JitterFull),
+			WithMinDelay(time.Millisecond*10),
+			WithMaxDelay(time.Minute),
+			WithMaxAttempts(7),
+			WithMaxDoublings(4),
+			WithName("db"),
+			WithMaxElapsed(time.Minute*5),
+			WithLimitAsFloor(),
+			WithSoftLimit(),
+			WithBaseDelayAlways(),
+			WithConstantFallback(0.1, time.Second*3),
+		),
+		"absolute jitter": CoerceNew(WithAbsoluteJitter(time.Millisecond * 50)),
+		"jitter bounds":   CoerceNew(WithJitterBounds(0, 0.3)),
+		"max jitter":      CoerceNew(WithMaxJitterAbsolute(time.Second * 5)),
+		"remaining settings": CoerceNew(
+			WithStartupSpread(time.Second),
+			WithSchedule(time.Millisecond, time.Millisecond*5),
+			WithPlateauPause(3, time.Minute),
+			WithGlobalSlots(time.Second, time.Millisecond*100),
+			WithOnExhausted(ExhaustedSleepPlateau),
+			WithLatencyMultiplier(2.5),
+			WithAdvanceOnCancel(true),
+			WithHistory(8),
+		),
+		"replay": ReplayBackoff([]time.Duration{time.Millisecond, time.Millisecond * 5}),
+	}
+	for name, b := range tests {
+		b.Next()
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		var got Backoff
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !reflect.DeepEqual(encodedSettings(&got), encodedSettings(b)) {
+			t.Fatalf("%s: got: %+v, want: %+v (from %s)", name, encodedSettings(&got), encodedSettings(b), data)
+		}
+		if got.PeekDelay() != b.InitialDelay() {
+			t.Fatalf("%s: expected the initial state, got delay %v", name, got.PeekDelay())
+		}
+	}
+}
+
+// encodedSettings returns the settings of the backoff that its encoded forms
+// represent, leaving out its random source, and the events in its history.
+func encodedSettings(b *Backoff) settings {
+	s := b.settings
+	s.rng = nil
+	if s.history != nil {
+		s.history = newHistory(len(s.history.buf))
+	}
+	return s
+}
+
+func TestJSONUnmarshal(t *testing.T) {
+	var b Backoff
+	if err := json.Unmarshal([]byte(`{"initialDelay":"1s","expLimit":"1m"}`), &b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Backoff{initial=1s, base=100ms, expLimit=1m0s, jitter=0.30}"; b.String() != want {
+		t.Fatalf("expected defaults for missing settings, got %v, want %v", &b, want)
+	}
+
+	tests := map[string]string{
+		"malformed duration":     `{"initialDelay":"soon"}`,
+		"negative initial delay": `{"initialDelay":"-1s"}`,
+		"zero base delay":        `{"baseDelay":"0s"}`,
+		"jitter factor above 1":  `{"jitterFactor":1.5}`,
+		"unknown growth":         `{"growth":"cubic"}`,
+		"unknown policy":         `{"onExhausted":"retry"}`,
+		"negative replay":        `{"replay":["-1s"]}`,
+		"min above the limit":    `{"expLimit":"1s","minDelay":"2s"}`,
+		"not an object":          `[]`,
+	}
+	for name, data := range tests {
+		before := b.String()
+		err := json.Unmarshal([]byte(data), &b)
+		if err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+		if b.String() != before {
+			t.Fatalf("%s: expected the backoff to be unchanged, got %v", name, &b)
+		}
+	}
+}
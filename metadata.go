@@ -0,0 +1,24 @@
+package backoff
+
+// SetMetadata attaches a caller-specific value to the backoff under the given
+// key, e.g. the name of the operation being retried, or a correlation ID, so
+// that it travels with the backoff through callbacks. Setting a key again
+// replaces its value. Metadata is not copied to derived backoffs.
+func (b *Backoff) SetMetadata(key string, value any) {
+	b.metaMu.Lock()
+	defer b.metaMu.Unlock()
+	if b.meta == nil {
+		b.meta = make(map[string]any)
+	}
+	b.meta[key] = value
+}
+
+// Metadata returns the value attached to the backoff under the given key, and
+// whether there was one. Unlike other methods, it is safe to call from the
+// backoff's callbacks, e.g. WithDelayOverride.
+func (b *Backoff) Metadata(key string) (any, bool) {
+	b.metaMu.Lock()
+	defer b.metaMu.Unlock()
+	v, ok := b.meta[key]
+	return v, ok
+}
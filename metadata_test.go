@@ -0,0 +1,37 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	var b *Backoff
+	var seen any
+	b = CoerceNew(
+		WithInitialDelay(time.Millisecond),
+		WithDelayOverride(func(attempt int, planned time.Duration) time.Duration {
+			seen, _ = b.Metadata("op")
+			return planned
+		}),
+	)
+	if _, ok := b.Metadata("op"); ok {
+		t.Fatal("expected no metadata before it is set")
+	}
+
+	b.SetMetadata("op", "fetch")
+	b.Sleep()
+	if seen != "fetch" {
+		t.Fatalf("expected the callback to see the metadata, got %v", seen)
+	}
+
+	b.SetMetadata("op", "store")
+	if v, ok := b.Metadata("op"); !ok || v != "store" {
+		t.Fatalf("expected the replaced value, got %v, %t", v, ok)
+	}
+	if _, ok := b.clone().Metadata("op"); ok {
+		t.Fatal("expected metadata not to be copied")
+	}
+}
@@ -0,0 +1,51 @@
+package backoff
+
+import "sync"
+
+// Pool is a pool of backoffs sharing the configuration of a template, which
+// amortizes allocations for servers that use a short-lived backoff per
+// request. It is safe for concurrent use.
+type Pool struct {
+	template *Backoff
+	pool     sync.Pool
+}
+
+// NewPool creates a pool of backoffs configured like the template. Later
+// changes to the template do not affect the pool.
+func NewPool(template *Backoff) *Pool {
+	p := &Pool{template: template.Clone()}
+	p.pool.New = func() any {
+		return p.template.Clone()
+	}
+	return p
+}
+
+// Get returns a backoff from the pool, configured like the template, and in
+// its initial state, without any metadata or history.
+func (p *Pool) Get() *Backoff {
+	b := p.pool.Get().(*Backoff)
+	// keep the backoff's own source of randomness, and the allocation of its
+	// history, though not the previous user's events
+	rng, h := b.rng, b.history
+	b.settings = p.template.settings
+	b.rng, b.history = rng, h
+	if h != nil {
+		h.clear()
+	}
+	b.reset()
+	// a new user starts like a new backoff, with its own startup spread
+	b.spreadDone = false
+	// metadata belongs to the previous user, though the map can be reused
+	b.metaMu.Lock()
+	clear(b.meta)
+	b.metaMu.Unlock()
+	return b
+}
+
+// Put returns a backoff to the pool, for reuse. The backoff must not be used
+// after it is returned.
+func (p *Pool) Put(b *Backoff) {
+	if b != nil {
+		p.pool.Put(b)
+	}
+}
@@ -0,0 +1,88 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	template := CoerceNew(WithInitialDelay(time.Second), WithMaxAttempts(3))
+	template.computeDelay()
+	p := NewPool(template)
+
+	for i := 0; i < 10; i++ {
+		b := p.Get()
+		if b.delay != time.Second || b.attempts != 0 {
+			t.Fatalf("expected a reset backoff, got delay: %v, attempts: %d", b.delay, b.attempts)
+		}
+		if b.maxAttempts != 3 {
+			t.Fatalf("expected the template configuration, got max attempts: %d", b.maxAttempts)
+		}
+
+		// leave the backoff dirty before returning it
+		b.computeDelay()
+		b.computeDelay()
+		p.Put(b)
+	}
+
+	// changes to the template do not affect the pool
+	WithMaxAttempts(5)(template, false)
+	if b := p.Get(); b.maxAttempts != 3 {
+		t.Fatalf("pool was affected by changes to the template")
+	}
+}
+
+func TestPoolClearsMetadata(t *testing.T) {
+	p := NewPool(CoerceNew())
+	b := p.Get()
+	b.SetMetadata("user", "alice")
+	p.Put(b)
+
+	// the pool may return the same backoff, or a new one, but never with the
+	// previous user's metadata
+	for i := 0; i < 10; i++ {
+		b := p.Get()
+		if v, ok := b.Metadata("user"); ok {
+			t.Fatalf("expected no metadata, got %v", v)
+		}
+		p.Put(b)
+	}
+}
+
+func TestPoolClearsHistory(t *testing.T) {
+	p := NewPool(CoerceNew(WithInitialDelay(time.Second), WithHistory(4), WithStartupSpread(time.Hour)))
+	b := p.Get()
+	b.Next()
+	b.Next()
+	p.Put(b)
+
+	for i := 0; i < 10; i++ {
+		b := p.Get()
+		if events := b.History(); len(events) != 0 {
+			t.Fatalf("expected no history, got %d events", len(events))
+		}
+		if b.spreadDone {
+			t.Fatalf("expected the startup spread to apply again to a new user")
+		}
+		b.Next()
+		p.Put(b)
+	}
+}
+
+func BenchmarkPool(b *testing.B) {
+	p := NewPool(CoerceNew())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bo := p.Get()
+		bo.computeDelay()
+		p.Put(bo)
+	}
+}
+
+func BenchmarkCoerceNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bo := CoerceNew()
+		bo.computeDelay()
+	}
+}
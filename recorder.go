@@ -0,0 +1,50 @@
+package backoff
+
+import (
+	"sync"
+	"time"
+)
+
+// Recorder captures the delays computed by a backoff, so tests of retry logic
+// can assert on the sequence of delays that was requested. It is safe for
+// concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	delays []time.Duration
+}
+
+// WithRecorder returns a copy of the backoff, with the same configuration and
+// progress, that records every delay it computes (after jitter) in the returned
+// Recorder. The original backoff is unaffected. Copies of the returned backoff
+// share its recorder.
+func (b *Backoff) WithRecorder() (*Backoff, *Recorder) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r := &Recorder{}
+	c := b.clone()
+	c.progress = b.progress
+	c.attempts = b.attempts
+	c.start = b.start
+	c.plateauRounds = b.plateauRounds
+	c.lastDelay = b.lastDelay
+	c.forced, c.forcedDelay = b.forced, b.forcedDelay
+	c.spreadDone = b.spreadDone
+	c.delaySum, c.finalDelay = b.delaySum, b.finalDelay
+	c.scriptNext = b.scriptNext
+	c.recorder = r
+	return c, r
+}
+
+// Delays returns the recorded delays, in the order they were computed.
+func (r *Recorder) Delays() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Duration(nil), r.delays...)
+}
+
+func (r *Recorder) record(d time.Duration) {
+	r.mu.Lock()
+	r.delays = append(r.delays, d)
+	r.mu.Unlock()
+}
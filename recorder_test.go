@@ -0,0 +1,41 @@
+package backoff
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Microsecond), WithJitterFactor(0), WithMaxAttempts(4))
+	rb, r := b.WithRecorder()
+	err := rb.Retry(context.Background(), func() error { return errTest })
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	want := []time.Duration{1000, 2000, 4000, 8000}
+	if got := r.Delays(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	if b.attempts != 0 {
+		t.Fatalf("recording advanced the original backoff")
+	}
+
+	// concurrent use through copies sharing the recorder
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(c *Backoff) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.computeDelay()
+			}
+		}(rb.clone())
+	}
+	wg.Wait()
+	if n := len(r.Delays()); n != len(want)+800 {
+		t.Fatalf("expected %d recorded delays, got %d", len(want)+800, n)
+	}
+}
@@ -0,0 +1,366 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"time"
+)
+
+type retryOption func(*retryConfig)
+
+type retryConfig struct {
+	initialSpread time.Duration
+	maxErrors     int
+	pauses        []pauseRule
+	retryable     func(error) bool
+	notify        func(error, time.Duration)
+}
+
+// pauseRule replaces the backoff with a fixed pause for matching errors.
+type pauseRule struct {
+	pred     func(error) bool
+	duration time.Duration
+}
+
+const defaultMaxErrors = 64
+
+func newRetryConfig(options []retryOption) *retryConfig {
+	c := &retryConfig{
+		maxErrors: defaultMaxErrors,
+	}
+	for i := 0; i < len(options); i++ {
+		options[i](c)
+	}
+	return c
+}
+
+// WithInitialSpread configuration RetryOption makes Retry sleep for a uniformly
+// random duration in [0, maxSpread] before the first invocation of the
+// operation. This staggers the initial burst of calls when many clients start
+// retrying at the same moment, e.g. when a recovered service comes back. It is
+// independent of the jitter applied between attempts. A maxSpread <= 0
+// disables the spread, which is the default.
+func WithInitialSpread(maxSpread time.Duration) retryOption {
+	return func(c *retryConfig) {
+		c.initialSpread = maxSpread
+	}
+}
+
+// WithMaxErrors configuration RetryOption caps the number of errors retained
+// by RetryCollect. Once the cap is reached, the oldest errors are discarded in
+// favour of the most recent ones. The cap must be > 0, and the default is 64.
+func WithMaxErrors(n int) retryOption {
+	return func(c *retryConfig) {
+		if n > 0 {
+			c.maxErrors = n
+		}
+	}
+}
+
+// WithPauseOn configuration RetryOption makes Retry pause for the fixed
+// duration, instead of the computed backoff delay, after an attempt whose
+// error satisfies pred. This supports honoring explicit signals such as a
+// maintenance window. The pause is still interrupted if the context is done,
+// and it does not advance the growth of the backoff. If several pause rules
+// match an error, the first one given is used.
+func WithPauseOn(pred func(error) bool, duration time.Duration) retryOption {
+	return func(c *retryConfig) {
+		if pred != nil {
+			c.pauses = append(c.pauses, pauseRule{pred, max(duration, 0)})
+		}
+	}
+}
+
+// WithRetryIf configuration RetryOption makes Retry stop immediately, and
+// return the error, after an attempt whose error does not satisfy retryable,
+// e.g. to retry server errors but not client errors. A nil retryable treats
+// every error as retryable, which is the default.
+func WithRetryIf(retryable func(error) bool) retryOption {
+	return func(c *retryConfig) {
+		c.retryable = retryable
+	}
+}
+
+// WithNotify configuration RetryOption makes Retry call notify after each
+// failed attempt that it is about to retry, with the error and the delay it is
+// about to wait for, e.g. to log a warning per transient failure. It is not
+// called when Retry gives up and returns the error instead, because the error
+// is not retryable, or the limits of the backoff are exhausted, though it is
+// called before a wait that the context then interrupts.
+func WithNotify(notify func(err error, delay time.Duration)) retryOption {
+	return func(c *retryConfig) {
+		c.notify = notify
+	}
+}
+
+// pauseFor returns the fixed pause for err, if any pause rule matches it.
+func (c *retryConfig) pauseFor(err error) (time.Duration, bool) {
+	for _, r := range c.pauses {
+		if r.pred(err) {
+			return r.duration, true
+		}
+	}
+	return 0, false
+}
+
+// PermanentError wraps an error that should not be retried. Retry and Do stop
+// immediately when fn returns one, and return the underlying error.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err in a PermanentError, so the retried function itself can
+// decide to give up. It returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// Retry calls fn until it returns nil, pausing between attempts using the
+// backoff. If the context is cancelled while waiting to retry, or the max
+// attempts of the backoff are exhausted, it returns the last error returned by
+// fn, or the context error if fn was never called. If fn returns a
+// PermanentError, it stops immediately and returns the underlying error.
+func (b *Backoff) Retry(ctx context.Context, fn func() error, options ...retryOption) error {
+	c := newRetryConfig(options)
+
+	if c.initialSpread > 0 {
+		if err := sleepContext(ctx, b.currentClock(), b.spreadDelay(c.initialSpread)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		var perm *PermanentError
+		if errors.As(err, &perm) {
+			return perm.Err
+		}
+		if c.retryable != nil && !c.retryable(err) {
+			return err
+		}
+		var clock Clock
+		d, paused := c.pauseFor(err)
+		if paused {
+			clock = b.currentClock()
+		} else {
+			var ctxErr error
+			if d, clock, ctxErr = b.advanceContext(ctx); ctxErr != nil || d == Stop {
+				return err
+			}
+		}
+		if c.notify != nil {
+			c.notify(err, d)
+		}
+		if sleepContext(ctx, clock, d) != nil {
+			return err
+		}
+	}
+}
+
+// SharedRetry calls fn until it returns nil, like Retry, and is safe for many
+// goroutines sharing one backoff, e.g. workers calling the same flaky
+// downstream, so that they all slow down together when it is struggling. Each
+// failed attempt, by any caller, advances the shared sequence of delays by one
+// round under the backoff's lock, and each success shrinks the shared delay by
+// one growth step (see Decrease), so the callers speed up again together as the
+// downstream recovers. The max attempts and the max elapsed time of the backoff
+// are shared too, bounding the attempts of all callers combined, until Reset.
+func (b *Backoff) SharedRetry(ctx context.Context, fn func() error, options ...retryOption) error {
+	err := b.Retry(ctx, fn, options...)
+	if err == nil {
+		b.Decrease()
+	}
+	return err
+}
+
+// RetryNotify calls fn until it returns nil, like Retry, but calls notify
+// after each failed attempt that it is about to retry, with the error and the
+// delay it is about to wait for. notify is not called for the final error that
+// RetryNotify returns when it gives up (see WithNotify).
+func (b *Backoff) RetryNotify(ctx context.Context, fn func() error, notify func(err error, delay time.Duration)) error {
+	return b.Retry(ctx, fn, WithNotify(notify))
+}
+
+// Do calls fn until it returns nil, like Retry, but stops immediately, and
+// returns the error, once fn returns an error for which isRetryable reports
+// false. A nil isRetryable treats every error as retryable.
+func (b *Backoff) Do(ctx context.Context, fn func() error, isRetryable func(error) bool) error {
+	return b.Retry(ctx, fn, WithRetryIf(isRetryable))
+}
+
+// All returns an iterator over the rounds of a retry loop, yielding the
+// attempt number and the delay that was waited before it:
+//
+//	for attempt, delay := range b.All(ctx) {
+//		if err := op(); err == nil {
+//			break
+//		}
+//		log.Printf("attempt %d failed after waiting %v", attempt, delay)
+//	}
+//
+// The first round is yielded immediately, as (0, 0). Before each later round
+// the iterator advances the backoff and sleeps, like SleepContext, and the
+// attempt number is the number of attempts made so far (see Attempts). It
+// stops when the context is done, or when the max attempts, or the max
+// elapsed time, have been exhausted.
+func (b *Backoff) All(ctx context.Context) iter.Seq2[int, time.Duration] {
+	return func(yield func(int, time.Duration) bool) {
+		if ctx.Err() != nil || !yield(0, 0) {
+			return
+		}
+		for {
+			b.mu.Lock()
+			if ctx.Err() != nil || b.done() {
+				b.mu.Unlock()
+				return
+			}
+			d := b.computeDelay()
+			attempt, notify, clock := b.attempts, b.retryHook(d), b.clock
+			b.mu.Unlock()
+
+			notify()
+			if sleepContext(ctx, clock, d) != nil || !yield(attempt, d) {
+				return
+			}
+		}
+	}
+}
+
+// RetryCollect behaves like Retry, except that on failure it returns the
+// errors from every attempt joined with errors.Join, rather than only the last
+// one, so each cause can still be matched with errors.Is. To bound memory use,
+// only the most recent errors are retained (see WithMaxErrors).
+func RetryCollect(ctx context.Context, b *Backoff, op func() error, options ...retryOption) error {
+	c := newRetryConfig(options)
+
+	var errs []error
+	err := b.Retry(ctx, func() error {
+		err := op()
+		if err != nil {
+			if len(errs) == c.maxErrors {
+				errs = append(errs[:0], errs[1:]...)
+			}
+			errs = append(errs, err)
+		}
+		return err
+	}, options...)
+	if err == nil || len(errs) == 0 {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// RetryUntilDeadline calls op until it returns nil, pausing between attempts
+// using the backoff, until the deadline. No pause extends past the deadline:
+// the final pause is shortened so that the last attempt happens right at the
+// deadline. If the deadline passes, or the max attempts of the backoff are
+// exhausted, before op succeeds, it returns the last error returned by op.
+// The deadline is compared using the monotonic clock, when it has a monotonic
+// reading (e.g. when derived from time.Now).
+func RetryUntilDeadline(op func() error, b *Backoff, deadline time.Time) error {
+	for {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		clock := b.currentClock()
+		remaining := deadline.Sub(clock.Now())
+		if remaining <= 0 || b.Done() {
+			return err
+		}
+		sleep(clock, min(b.Next(), remaining))
+	}
+}
+
+// RetryWithSleep calls op until it returns nil, pausing between attempts by
+// calling sleep with each delay computed by the backoff, rather than blocking
+// on a timer. This lets schedulers and simulations that manage their own
+// (e.g. virtual) time drive the retries. If the max attempts of the backoff
+// are exhausted, it returns the last error returned by op. Without a max
+// attempts limit, it retries until op succeeds.
+func RetryWithSleep(b *Backoff, op func() error, sleep func(time.Duration)) error {
+	for {
+		err := op()
+		if err == nil || b.Done() {
+			return err
+		}
+		sleep(b.Next())
+	}
+}
+
+// RetryLimited retries op for each of the items, with each item backing off
+// independently on failure using its own copy of b (in its initial state),
+// while never running more than maxConcurrent items at once. It returns once
+// every item has succeeded, or has given up because the context was cancelled
+// or the max attempts were exhausted, and the errors of the items that gave up
+// are returned joined with errors.Join. The maxConcurrent must be > 0.
+func RetryLimited[T any](ctx context.Context, b *Backoff, maxConcurrent int, items []T, op func(context.Context, T) error) error {
+	if maxConcurrent <= 0 {
+		return errors.New("the max concurrency must be > 0")
+	}
+
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, maxConcurrent)
+	var wg sync.WaitGroup
+	for i := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, ib *Backoff) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = ib.Retry(ctx, func() error {
+				return op(ctx, items[i])
+			})
+		}(i, b.Clone())
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// spreadDelay returns a uniformly random duration in [0, limit], drawn from
+// the backoff's source of randomness.
+func (b *Backoff) spreadDelay(limit time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Duration(b.rng.Int63n(int64(limit) + 1))
+}
+
+// sleepContext pauses for d, as measured by the clock, or until the context is
+// done, whichever is first.
+func sleepContext(ctx context.Context, c Clock, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t := c.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C():
+		return nil
+	}
+}
@@ -0,0 +1,536 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test error")
+
+func TestRetry(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Millisecond), WithJitterFactor(0))
+	calls := 0
+	err := b.Retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return errTest
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+
+	// cancellation returns the last error from the operation
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err = b.Retry(ctx, func() error { return errTest })
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected %v, got %v", errTest, err)
+	}
+}
+
+func TestRetryInitialSpread(t *testing.T) {
+	const spread = time.Millisecond * 30
+	b := CoerceNew()
+	for i := 0; i < 1000; i++ {
+		if d := b.spreadDelay(spread); d < 0 || d > spread {
+			t.Fatalf("spread delay out of range [0,%v]: %v", spread, d)
+		}
+	}
+
+	// the spread is drawn from the backoff's source of randomness
+	b1 := CoerceNew(WithRandSource(rand.New(rand.NewSource(1))))
+	b2 := CoerceNew(WithRandSource(rand.New(rand.NewSource(1))))
+	for i := 0; i < 10; i++ {
+		if d1, d2 := b1.spreadDelay(time.Hour), b2.spreadDelay(time.Hour); d1 != d2 {
+			t.Fatalf("expected the same spread from the same source, got %v and %v", d1, d2)
+		}
+	}
+
+	start := time.Now()
+	var firstCall time.Duration
+	err := b.Retry(context.Background(), func() error {
+		firstCall = time.Since(start)
+		return nil
+	}, WithInitialSpread(spread))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if firstCall > spread+time.Millisecond*20 {
+		t.Fatalf("first call delayed by %v, expected at most ~%v", firstCall, spread)
+	}
+
+	// cancellation during the spread means the operation is never called
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = b.Retry(ctx, func() error {
+		t.Fatal("operation called despite cancelled context")
+		return nil
+	}, WithInitialSpread(spread))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestRetryCollect(t *testing.T) {
+	causes := []error{
+		errors.New("dns failure"),
+		errors.New("timeout"),
+		errors.New("connection refused"),
+	}
+	b := CoerceNew(WithInitialDelay(time.Millisecond), WithExponentialLimit(time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	i := 0
+	err := RetryCollect(ctx, b, func() error {
+		err := causes[i%len(causes)]
+		i++
+		return err
+	})
+	for _, cause := range causes {
+		if !errors.Is(err, cause) {
+			t.Fatalf("expected collected errors to include %v, got %v", cause, err)
+		}
+	}
+
+	// only the most recent errors are retained
+	b = CoerceNew(WithInitialDelay(time.Millisecond), WithExponentialLimit(time.Millisecond))
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	i = 0
+	err = RetryCollect(ctx, b, func() error {
+		err := causes[min(i, len(causes)-1)]
+		i++
+		return err
+	}, WithMaxErrors(2))
+	if errors.Is(err, causes[0]) || !errors.Is(err, causes[2]) {
+		t.Fatalf("expected only the most recent errors, got %v", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 2 {
+		t.Fatalf("expected 2 collected errors, got %d", n)
+	}
+
+	// success returns nil
+	if err := RetryCollect(context.Background(), b, func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRetryPauseOn(t *testing.T) {
+	const pause = time.Millisecond * 30
+	errMaintenance := errors.New("maintenance")
+	isMaintenance := func(err error) bool { return errors.Is(err, errMaintenance) }
+
+	tests := map[string]struct {
+		err        error
+		minElapsed time.Duration
+		wantDelay  time.Duration
+	}{
+		"matching error pauses without growth": {errMaintenance, pause, time.Millisecond},
+		"other errors use the normal backoff":  {errTest, 0, time.Millisecond * 2},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			b := CoerceNew(WithInitialDelay(time.Millisecond), WithJitterFactor(0))
+			calls := 0
+			start := time.Now()
+			err := b.Retry(context.Background(), func() error {
+				calls++
+				if calls == 1 {
+					return tc.err
+				}
+				return nil
+			}, WithPauseOn(isMaintenance, pause))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < tc.minElapsed {
+				t.Fatalf("expected a pause of at least %v, got %v", tc.minElapsed, elapsed)
+			}
+			if b.delay != tc.wantDelay {
+				t.Fatalf("expected next delay of %v, got %v", tc.wantDelay, b.delay)
+			}
+		})
+	}
+
+	// the pause is interrupted by the context
+	b := CoerceNew()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	start := time.Now()
+	err := b.Retry(ctx, func() error { return errMaintenance }, WithPauseOn(isMaintenance, time.Hour))
+	if !errors.Is(err, errMaintenance) {
+		t.Fatalf("expected %v, got %v", errMaintenance, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("pause was not interrupted by the context, took %v", elapsed)
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Millisecond), WithMaxAttempts(2))
+	calls := 0
+	err := b.Retry(context.Background(), func() error {
+		calls++
+		return errTest
+	})
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected %v, got %v", errTest, err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls (2 retries), got %d", calls)
+	}
+}
+
+func TestRetryUntilDeadline(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	b := CoerceNew(WithInitialDelay(time.Millisecond*20), WithJitterFactor(0), WithClock(clock))
+	start := clock.Now()
+	deadline := start.Add(time.Millisecond * 50)
+	var calls []time.Time
+	err := RetryUntilDeadline(func() error {
+		calls = append(calls, clock.Now())
+		return errTest
+	}, b, deadline)
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected %v, got %v", errTest, err)
+	}
+
+	// attempts at 0ms, 20ms, and the last clamped to the deadline at 50ms
+	want := []time.Time{start, start.Add(time.Millisecond * 20), deadline}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got attempts at %v, want %v", calls, want)
+	}
+
+	// success before the deadline
+	calls = nil
+	err = RetryUntilDeadline(func() error {
+		calls = append(calls, clock.Now())
+		if len(calls) < 2 {
+			return errTest
+		}
+		return nil
+	}, CoerceNew(WithInitialDelay(time.Millisecond), WithClock(clock)), clock.Now().Add(time.Second))
+	if err != nil || len(calls) != 2 {
+		t.Fatalf("expected success on the second attempt, got %d attempts, err: %v", len(calls), err)
+	}
+
+	// a deadline in the past allows a single attempt
+	calls = nil
+	err = RetryUntilDeadline(func() error {
+		calls = append(calls, clock.Now())
+		return errTest
+	}, CoerceNew(WithClock(clock)), clock.Now().Add(-time.Second))
+	if !errors.Is(err, errTest) || len(calls) != 1 {
+		t.Fatalf("expected a single failed attempt, got %d attempts, err: %v", len(calls), err)
+	}
+}
+
+func TestRetryLimited(t *testing.T) {
+	const (
+		nItems        = 20
+		maxConcurrent = 3
+	)
+	items := make([]int, nItems)
+	for i := range items {
+		items[i] = i
+	}
+
+	var (
+		mu       sync.Mutex
+		inFlight int
+		peak     int
+		failures = make(map[int]int)
+	)
+	b := CoerceNew(WithInitialDelay(time.Millisecond))
+	err := RetryLimited(context.Background(), b, maxConcurrent, items, func(_ context.Context, item int) error {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		inFlight--
+		if failures[item] < 2 {
+			failures[item]++
+			return errTest
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if peak > maxConcurrent {
+		t.Fatalf("concurrency of %d exceeded the limit of %d", peak, maxConcurrent)
+	}
+	for _, item := range items {
+		if failures[item] != 2 {
+			t.Fatalf("item %d was not retried to success", item)
+		}
+	}
+	if b.attempts != 0 {
+		t.Fatalf("items advanced the shared backoff")
+	}
+
+	// cancellation stops the items that keep failing
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err = RetryLimited(ctx, b, maxConcurrent, items, func(context.Context, int) error {
+		return errTest
+	})
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected %v, got %v", errTest, err)
+	}
+
+	if err := RetryLimited(ctx, b, 0, items, func(context.Context, int) error { return nil }); err == nil {
+		t.Fatalf("expected error for a max concurrency of 0")
+	}
+}
+
+func TestSharedRetry(t *testing.T) {
+	ms := time.Millisecond
+	b := CoerceNew(WithInitialDelay(ms), WithBaseDelay(ms), WithJitterFactor(0))
+	n := 0
+	err := b.SharedRetry(context.Background(), func() error {
+		if n++; n < 3 {
+			return errTest
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := b.PeekDelay(); d != ms*2 {
+		t.Fatalf("expected the success to shrink the delay to 2ms, got %v", d)
+	}
+
+	// concurrent workers advance one shared sequence of delays
+	const (
+		nWorkers = 8
+		nFails   = 3
+	)
+	shared := CoerceNew(
+		WithInitialDelay(time.Microsecond*100),
+		WithBaseDelay(time.Microsecond*100),
+		WithExponentialLimit(ms),
+		WithJitterFactor(0),
+	)
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, nWorkers)
+	)
+	for i := 0; i < nWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fails := 0
+			errs[i] = shared.SharedRetry(context.Background(), func() error {
+				if fails < nFails {
+					fails++
+					return errTest
+				}
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("worker %d: unexpected error: %v", i, err)
+		}
+	}
+	if got := shared.Attempts(); got != nWorkers*nFails {
+		t.Fatalf("expected %d shared attempts, got %d", nWorkers*nFails, got)
+	}
+	// the last call on the shared backoff was a success, which shrank the delay
+	// from at most 1.6ms, the first delay past the limit
+	if d := shared.PeekDelay(); d > time.Microsecond*800 {
+		t.Fatalf("expected the final success to shrink the delay, got %v", d)
+	}
+
+	// the max attempts are shared by all callers
+	limited := CoerceNew(WithInitialDelay(0), WithMaxAttempts(2))
+	for i := 0; i < 2; i++ {
+		if err := limited.SharedRetry(context.Background(), func() error { return errTest }); !errors.Is(err, errTest) {
+			t.Fatalf("expected %v, got %v", errTest, err)
+		}
+	}
+	if got := limited.Attempts(); got != 2 {
+		t.Fatalf("expected the callers to share 2 attempts, got %d", got)
+	}
+}
+
+func TestRetryWithSleep(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Second), WithJitterFactor(0), WithExponentialLimit(time.Second*4))
+	var slept []time.Duration
+	calls := 0
+	err := RetryWithSleep(b, func() error {
+		calls++
+		if calls <= 5 {
+			return errTest
+		}
+		return nil
+	}, func(d time.Duration) {
+		slept = append(slept, d)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 4, time.Second * 4}
+	if !reflect.DeepEqual(slept, want) {
+		t.Fatalf("got: %v, want: %v", slept, want)
+	}
+
+	// gives up once the max attempts are exhausted
+	b = CoerceNew(WithMaxAttempts(2))
+	slept = nil
+	err = RetryWithSleep(b, func() error { return errTest }, func(d time.Duration) {
+		slept = append(slept, d)
+	})
+	if !errors.Is(err, errTest) || len(slept) != 2 {
+		t.Fatalf("expected 2 sleeps and %v, got %d sleeps and %v", errTest, len(slept), err)
+	}
+}
+
+func TestDo(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	isRetryable := func(err error) bool { return !errors.Is(err, errPermanent) }
+
+	tests := map[string]struct {
+		errs        []error
+		isRetryable func(error) bool
+		wantErr     error
+		wantCalls   int
+	}{
+		"retryable errors are retried":      {[]error{errTest, errTest}, isRetryable, nil, 3},
+		"non-retryable error stops at once": {[]error{errTest, errPermanent, errTest}, isRetryable, errPermanent, 2},
+		"nil predicate retries every error": {[]error{errPermanent, errTest}, nil, nil, 3},
+	}
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			b := CoerceNew(WithInitialDelay(time.Millisecond), WithJitterFactor(0))
+			calls := 0
+			err := b.Do(context.Background(), func() error {
+				calls++
+				if calls <= len(tc.errs) {
+					return tc.errs[calls-1]
+				}
+				return nil
+			}, tc.isRetryable)
+			if err != tc.wantErr {
+				t.Errorf("got error %v, want %v", err, tc.wantErr)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryPermanent(t *testing.T) {
+	if Permanent(nil) != nil {
+		t.Error("expected Permanent(nil) to be nil")
+	}
+
+	perm := Permanent(errTest)
+	if !errors.Is(perm, errTest) {
+		t.Error("expected the permanent error to wrap the underlying error")
+	}
+
+	b := CoerceNew(WithInitialDelay(time.Millisecond), WithJitterFactor(0))
+	calls := 0
+	err := b.Do(context.Background(), func() error {
+		calls++
+		if calls == 2 {
+			return fmt.Errorf("giving up: %w", perm)
+		}
+		return errTest
+	}, nil)
+	if err != errTest {
+		t.Errorf("got error %v, want the unwrapped %v", err, errTest)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
+
+func TestAll(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Millisecond), WithJitterFactor(0), WithMaxAttempts(3))
+
+	var attempts []int
+	var delays []time.Duration
+	for attempt, delay := range b.All(context.Background()) {
+		attempts = append(attempts, attempt)
+		delays = append(delays, delay)
+	}
+	wantDelays := []time.Duration{0, time.Millisecond, time.Millisecond * 2, time.Millisecond * 4}
+	if !reflect.DeepEqual(attempts, []int{0, 1, 2, 3}) || !reflect.DeepEqual(delays, wantDelays) {
+		t.Fatalf("got attempts %v and delays %v, want [0 1 2 3] and %v", attempts, delays, wantDelays)
+	}
+
+	// breaking out stops the iterator without advancing further
+	b.Reset()
+	for attempt := range b.All(context.Background()) {
+		if attempt == 1 {
+			break
+		}
+	}
+	if b.Attempts() != 1 {
+		t.Fatalf("got %d attempts, want 1", b.Attempts())
+	}
+
+	// a cancelled context interrupts the sleep
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	slow := CoerceNew(WithInitialDelay(time.Hour))
+	rounds := 0
+	for range slow.All(ctx) {
+		rounds++
+	}
+	if rounds != 1 {
+		t.Fatalf("got %d rounds, want 1", rounds)
+	}
+}
+
+func TestRetryNotify(t *testing.T) {
+	b := CoerceNew(WithInitialDelay(time.Millisecond), WithJitterFactor(0), WithMaxAttempts(2))
+
+	type note struct {
+		err   error
+		delay time.Duration
+	}
+	var notes []note
+	calls := 0
+	err := b.RetryNotify(context.Background(), func() error {
+		calls++
+		return errTest
+	}, func(err error, delay time.Duration) {
+		notes = append(notes, note{err, delay})
+	})
+	if err != errTest || calls != 3 {
+		t.Fatalf("got %v after %d calls, want the last error after 3 calls", err, calls)
+	}
+	// no notification for the final error, once the attempts are exhausted
+	want := []note{{errTest, time.Millisecond}, {errTest, time.Millisecond * 2}}
+	if !reflect.DeepEqual(notes, want) {
+		t.Fatalf("got notifications %v, want %v", notes, want)
+	}
+}
@@ -0,0 +1,58 @@
+package backoff
+
+import "time"
+
+const (
+	sloBaseMultiple = 2                // base delay, as a multiple of the p99 latency
+	sloLimitShare   = 4                // exponential limit, as a fraction of the budget
+	sloMinDelay     = time.Millisecond // floor on the base delay
+)
+
+// FromSLO returns a backoff derived from a latency SLO: the p99 latency of the
+// downstream, and the total time budget for retries. It returns nil if p99 is
+// negative, or the budget is too small to fit even a 1ns delay with jitter.
+//
+// The formula is:
+//
+//   - the initial and base delays are 2*p99 (at least 1ms), so a retry is
+//     unlikely to overlap a slow but still successful attempt, capped so that
+//     the first delay, with jitter, fits within the budget
+//   - the jitter factor is the default of 0.3
+//   - the exponential limit is budget/4, so the backoff still makes a few
+//     attempts once it reaches the plateau
+//   - the max attempts is the largest count for which the worst-case delays,
+//     i.e. at the upper edge of the jitter band, add up to at most the budget
+func FromSLO(p99 time.Duration, budget time.Duration) *Backoff {
+	if p99 < 0 {
+		return nil
+	}
+	b := defaultBackoff()
+	firstMax := time.Duration(float64(budget) / (1 + b.jitterFactor/2))
+	if firstMax <= 0 {
+		return nil
+	}
+	delay := min(max(p99*sloBaseMultiple, sloMinDelay), firstMax)
+	b.initDelay, b.baseDelay, b.delay = delay, delay, delay
+	b.expLimit = max(budget/sloLimitShare, delay)
+
+	var total float64
+	for p := b.progress; ; p = b.grow(p) {
+		_, hi := b.jitterRange(p.delay, false)
+		if total+hi > float64(budget) {
+			break
+		}
+		if b.plateaued(p) {
+			// the remaining delays are all the same, so count them at once
+			b.maxAttempts += int((float64(budget) - total) / hi)
+			break
+		}
+		total += hi
+		b.maxAttempts++
+	}
+	if b.maxAttempts == 0 {
+		// rounding left no room for even the first delay
+		b.maxAttempts = 1
+		b.initDelay, b.delay = 0, 0
+	}
+	return b
+}
@@ -0,0 +1,58 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFromSLO(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		p99       time.Duration
+		budget    time.Duration
+		wantBase  time.Duration
+		wantValid bool
+	}{
+		{"typical", time.Millisecond * 200, time.Second * 5, time.Millisecond * 400, true},
+		{"zero latency", 0, time.Second, time.Millisecond, true},
+		{"tight budget", time.Second, time.Millisecond * 500, 0, true},
+		{"huge budget", time.Millisecond, time.Hour * 24, time.Millisecond * 2, true},
+		{"negative latency", -1, time.Second, 0, false},
+		{"no budget", time.Millisecond, 0, 0, false},
+		{"budget below a jittered nanosecond", 0, time.Nanosecond, 0, false},
+		{"tiny budget", 0, time.Nanosecond * 10, time.Nanosecond * 8, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := FromSLO(tt.p99, tt.budget)
+			if (b != nil) != tt.wantValid {
+				t.Fatalf("expected valid %t, got %v", tt.wantValid, b)
+			}
+			if b == nil {
+				return
+			}
+			if tt.wantBase != 0 && b.baseDelay != tt.wantBase {
+				t.Fatalf("expected base delay %v, got %v", tt.wantBase, b.baseDelay)
+			}
+			if b.maxAttempts < 1 {
+				t.Fatalf("expected at least one attempt, got %d", b.maxAttempts)
+			}
+
+			var total time.Duration
+			for i := 0; i < b.maxAttempts; i++ {
+				total += b.WorstCaseDelay(i)
+			}
+			if total > tt.budget {
+				t.Fatalf("expected worst case %v within budget %v", total, tt.budget)
+			}
+			if next := total + b.WorstCaseDelay(b.maxAttempts); next <= tt.budget {
+				t.Fatalf("expected one more attempt to exceed the budget, got %v", next)
+			}
+		})
+	}
+}
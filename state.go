@@ -0,0 +1,68 @@
+package backoff
+
+import "time"
+
+// State is the progress of a backoff through its sequence of delays, as
+// opposed to its configuration, so that a long-running retry loop can persist
+// it and resume after a restart. It can be encoded as JSON, with durations in
+// nanoseconds.
+type State struct {
+	Delay         time.Duration `json:"delay"`                   // the next delay, before jitter
+	Prev          time.Duration `json:"prev,omitempty"`          // the delay before it, for Fibonacci growth
+	Rounds        int           `json:"rounds,omitempty"`        // the rounds since the initial delay
+	Grown         int           `json:"grown,omitempty"`         // the growth steps, for WithMaxDoublings
+	Scripted      int           `json:"scripted,omitempty"`      // the scheduled delays already used
+	Attempts      int           `json:"attempts,omitempty"`      // the attempts, for WithMaxAttempts
+	Elapsed       time.Duration `json:"elapsed,omitempty"`       // the time since the first round, for WithMaxElapsed
+	LastDelay     time.Duration `json:"lastDelay,omitempty"`     // the last delay, for JitterDecorrelated
+	PlateauRounds int           `json:"plateauRounds,omitempty"` // the rounds at the plateau, for WithPlateauPause
+}
+
+// Snapshot returns the current progress of the backoff, to be restored later
+// with Restore, e.g. by a fresh backoff with the same configuration after a
+// process restart. The elapsed time is measured by the backoff's clock, and
+// is 0 until the first round.
+func (b *Backoff) Snapshot() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := State{
+		Delay:         b.delay,
+		Prev:          b.prev,
+		Rounds:        b.rounds,
+		Grown:         b.grown,
+		Scripted:      b.scriptNext,
+		Attempts:      b.attempts,
+		LastDelay:     b.lastDelay,
+		PlateauRounds: b.plateauRounds,
+	}
+	if !b.start.IsZero() {
+		s.Elapsed = b.clock.Now().Sub(b.start)
+	}
+	return s
+}
+
+// Restore resumes the backoff from the progress in s, as returned by Snapshot,
+// so that its next delay is the one the snapshotted backoff would have used.
+// The elapsed time counts towards any max elapsed time from now on, and the
+// startup spread is not applied again once the backoff has advanced. Like
+// Reset, it clears the stats and any pending Override, which are not part of
+// the snapshot. Negative values in s are treated as 0.
+func (b *Backoff) Restore(s State) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+	b.progress = progress{
+		delay:  max(s.Delay, 0),
+		prev:   max(s.Prev, 0),
+		rounds: max(s.Rounds, 0),
+		grown:  max(s.Grown, 0),
+	}
+	b.scriptNext = min(max(s.Scripted, 0), len(b.script))
+	b.attempts = max(s.Attempts, 0)
+	b.lastDelay = max(s.LastDelay, 0)
+	b.plateauRounds = max(s.PlateauRounds, 0)
+	b.spreadDone = b.spreadDone || b.attempts > 0
+	if b.attempts > 0 || s.Elapsed > 0 {
+		b.start = b.clock.Now().Add(-max(s.Elapsed, 0))
+	}
+}
@@ -0,0 +1,92 @@
+package backoff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	tests := map[string][]backoffOption{
+		"exponential":    nil,
+		"fibonacci":      {WithGrowth(GrowthFibonacci)},
+		"doublings":      {WithMaxDoublings(3)},
+		"scheduled":      {WithSchedule(time.Millisecond, time.Millisecond*5, time.Millisecond*7, time.Millisecond*9)},
+		"base always":    {WithInitialDelay(time.Second), WithBaseDelayAlways()},
+		"startup spread": {WithStartupSpread(time.Hour)},
+		"plateau pause":  {WithInitialDelay(time.Second), WithExponentialLimit(time.Second), WithPlateauPause(3, time.Minute)},
+	}
+	for name, options := range tests {
+		options = append(options, WithJitterFactor(0), WithMaxAttempts(10))
+		b := CoerceNew(options...)
+		for i := 0; i < 3; i++ {
+			b.Next()
+		}
+
+		data, err := json.Marshal(b.Snapshot())
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		var s State
+		if err := json.Unmarshal(data, &s); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		restored := CoerceNew(options...)
+		restored.Restore(s)
+
+		if restored.Attempts() != 3 {
+			t.Fatalf("%s: expected 3 attempts, got %d", name, restored.Attempts())
+		}
+		var want, got []time.Duration
+		for d := b.NextDelay(); d != Stop; d = b.NextDelay() {
+			want = append(want, d)
+		}
+		for d := restored.NextDelay(); d != Stop; d = restored.NextDelay() {
+			got = append(got, d)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRestoreDecorrelated(t *testing.T) {
+	b := CoerceNew(WithJitterMode(JitterDecorrelated), WithExponentialLimit(time.Hour))
+	for i := 0; i < 5; i++ {
+		b.Next()
+	}
+	restored := CoerceNew(WithJitterMode(JitterDecorrelated), WithExponentialLimit(time.Hour))
+	restored.Restore(b.Snapshot())
+
+	wantLo, wantHi := b.PeekRange()
+	if lo, hi := restored.PeekRange(); lo != wantLo || hi != wantHi {
+		t.Fatalf("expected the next delay in [%v, %v], got [%v, %v]", wantLo, wantHi, lo, hi)
+	}
+}
+
+func TestRestoreElapsed(t *testing.T) {
+	now := time.Now()
+	b := CoerceNew(WithMaxElapsed(time.Minute))
+	b.clock = nowClock(func() time.Time { return now })
+	if s := b.Snapshot(); s.Elapsed != 0 {
+		t.Fatalf("expected no elapsed time before the first round, got %v", s.Elapsed)
+	}
+	b.Next()
+	now = now.Add(time.Second * 40)
+	s := b.Snapshot()
+	if s.Elapsed != time.Second*40 {
+		t.Fatalf("expected 40s elapsed, got %v", s.Elapsed)
+	}
+
+	restored := CoerceNew(WithMaxElapsed(time.Minute))
+	restored.clock = nowClock(func() time.Time { return now })
+	restored.Restore(s)
+	if restored.Done() {
+		t.Fatalf("expected the elapsed time to remain within the max")
+	}
+	now = now.Add(time.Second * 20)
+	if !restored.Done() {
+		t.Fatalf("expected the restored elapsed time to count towards the max")
+	}
+}
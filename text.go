@@ -0,0 +1,156 @@
+package backoff
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// textField is a setting in the text form of a Backoff's configuration,
+// pointing into its encodable form.
+type textField struct {
+	key string
+	ptr any // *string, *[]string, **float64, *float64, *int or *bool
+}
+
+// textFields returns the settings of j, in the order of the text form.
+func (j *backoffJSON) textFields() []textField {
+	return []textField{
+		{"name", &j.Name},
+		{"init", &j.InitialDelay},
+		{"base", &j.BaseDelay},
+		{"limit", &j.ExpLimit},
+		{"jitter", &j.JitterFactor},
+		{"multiplier", &j.Multiplier},
+		{"growth", &j.Growth},
+		{"mode", &j.JitterMode},
+		{"absJitter", &j.AbsoluteJitter},
+		{"jitterLow", &j.JitterLow},
+		{"jitterHigh", &j.JitterHigh},
+		{"maxJitter", &j.MaxJitter},
+		{"min", &j.MinDelay},
+		{"max", &j.MaxDelay},
+		{"doublings", &j.MaxDoublings},
+		{"attempts", &j.MaxAttempts},
+		{"elapsed", &j.MaxElapsed},
+		{"floor", &j.LimitAsFloor},
+		{"soft", &j.SoftLimit},
+		{"baseAlways", &j.BaseDelayAlways},
+		{"fallbackProb", &j.FallbackProb},
+		{"fallbackDelay", &j.FallbackDelay},
+		{"spread", &j.StartupSpread},
+		{"schedule", &j.Schedule},
+		{"replay", &j.Replay},
+		{"pauseAfter", &j.PauseAfter},
+		{"pause", &j.PauseDelay},
+		{"slot", &j.Slot},
+		{"slotOffset", &j.SlotOffset},
+		{"exhausted", &j.OnExhausted},
+		{"latencyMultiplier", &j.LatencyMultiplier},
+		{"advanceOnCancel", &j.AdvanceOnCancel},
+		{"history", &j.HistorySize},
+	}
+}
+
+// MarshalText encodes the configuration of the backoff in a compact text form
+// of semicolon-separated settings, e.g.
+// "init=100ms;base=500ms;limit=1m0s;jitter=0.3", for configuration through
+// environment variables. Like MarshalJSON, it includes the settings that take
+// non-default values, among those that can be represented, and lists of
+// delays are separated by commas, e.g. "schedule=1ms,5ms". A name containing a
+// semicolon cannot be represented, and returns an error.
+func (b *Backoff) MarshalText() ([]byte, error) {
+	b.mu.Lock()
+	j := b.config()
+	b.mu.Unlock()
+	if strings.Contains(j.Name, ";") {
+		return nil, errors.New("the name must not contain ';' in the text form")
+	}
+
+	var parts []string
+	for _, f := range j.textFields() {
+		var v string
+		switch p := f.ptr.(type) {
+		case *string:
+			v = *p
+		case *[]string:
+			v = strings.Join(*p, ",")
+		case **float64:
+			if *p != nil {
+				v = strconv.FormatFloat(**p, 'g', -1, 64)
+			}
+		case *float64:
+			if *p != 0 {
+				v = strconv.FormatFloat(*p, 'g', -1, 64)
+			}
+		case *int:
+			if *p != 0 {
+				v = strconv.Itoa(*p)
+			}
+		case *bool:
+			if *p {
+				v = "true"
+			}
+		}
+		if v != "" {
+			parts = append(parts, f.key+"="+v)
+		}
+	}
+	return []byte(strings.Join(parts, ";")), nil
+}
+
+// UnmarshalText replaces the configuration of the backoff with the one encoded
+// by MarshalText, and restores it to its initial state. Settings that are
+// missing take their default values, and unknown settings are an error. The
+// settings are validated as by New, so an invalid configuration returns an
+// error, leaving the backoff unchanged.
+func (b *Backoff) UnmarshalText(text []byte) error {
+	var j backoffJSON
+	fields := make(map[string]any)
+	for _, f := range j.textFields() {
+		fields[f.key] = f.ptr
+	}
+
+	var errs error
+	for _, part := range strings.Split(string(text), ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, v, ok := strings.Cut(part, "=")
+		key, v = strings.TrimSpace(key), strings.TrimSpace(v)
+		ptr, known := fields[key]
+		if !ok || !known {
+			errs = errors.Join(errs, fmt.Errorf("invalid setting %q", part))
+			continue
+		}
+
+		var err error
+		switch p := ptr.(type) {
+		case *string:
+			*p = v
+		case *[]string:
+			for _, s := range strings.Split(v, ",") {
+				*p = append(*p, strings.TrimSpace(s))
+			}
+		case **float64:
+			var f float64
+			f, err = strconv.ParseFloat(v, 64)
+			*p = &f
+		case *float64:
+			*p, err = strconv.ParseFloat(v, 64)
+		case *int:
+			*p, err = strconv.Atoi(v)
+		case *bool:
+			*p, err = strconv.ParseBool(v)
+		}
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("invalid %s: %w", key, err))
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return b.setConfig(j)
+}
@@ -0,0 +1,90 @@
+package backoff
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTextRoundTrip(t *testing.T) {
+	tests := map[string]*Backoff{
+		"defaults": CoerceNew(),
+		"all settings": CoerceNew(
+			WithName("db"),
+			WithMultiplier(1.5),
+			WithGrowth(GrowthFibonacci),
+			WithJitterMode(JitterFull),
+			WithMinDelay(time.Millisecond*10),
+			WithMaxDelay(time.Minute),
+			WithMaxDoublings(4),
+			WithMaxAttempts(7),
+			WithMaxElapsed(time.Minute*5),
+			WithLimitAsFloor(),
+			WithSoftLimit(),
+			WithBaseDelayAlways(),
+			WithConstantFallback(0.1, time.Second*3),
+		),
+		"absolute jitter": CoerceNew(WithAbsoluteJitter(time.Millisecond * 50)),
+		"jitter bounds":   CoerceNew(WithJitterBounds(0, 0.3)),
+		"max jitter":      CoerceNew(WithMaxJitterAbsolute(time.Second * 5)),
+		"remaining settings": CoerceNew(
+			WithStartupSpread(time.Second),
+			WithSchedule(time.Millisecond, time.Millisecond*5),
+			WithPlateauPause(3, time.Minute),
+			WithGlobalSlots(time.Second, time.Millisecond*100),
+			WithOnExhausted(ExhaustedSleepPlateau),
+			WithLatencyMultiplier(2.5),
+			WithAdvanceOnCancel(true),
+			WithHistory(8),
+		),
+		"replay": ReplayBackoff([]time.Duration{time.Millisecond, time.Millisecond * 5}),
+	}
+	for name, b := range tests {
+		b.Next()
+		text, err := b.MarshalText()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		var got Backoff
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !reflect.DeepEqual(encodedSettings(&got), encodedSettings(b)) {
+			t.Fatalf("%s: got: %+v, want: %+v (from %s)", name, encodedSettings(&got), encodedSettings(b), text)
+		}
+	}
+
+	if text, _ := CoerceNew().MarshalText(); string(text) != "init=100ms;base=100ms;limit=3m0s;jitter=0.3" {
+		t.Fatalf("unexpected text form of the defaults: %s", text)
+	}
+	if _, err := CoerceNew(WithName("a;b")).MarshalText(); err == nil {
+		t.Fatal("expected an error for a name containing a semicolon")
+	}
+}
+
+func TestUnmarshalText(t *testing.T) {
+	var b Backoff
+	if err := b.UnmarshalText([]byte(" init=100ms; base=500ms;limit=60s;jitter=0.3; ")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Backoff{initial=100ms, base=500ms, expLimit=1m0s, jitter=0.30}"; b.String() != want {
+		t.Fatalf("got: %v, want: %v", &b, want)
+	}
+
+	for _, text := range []string{
+		"init=100ms;nope=1",
+		"init",
+		"init=soon",
+		"attempts=many",
+		"jitter=1.5",
+		"min=2m;limit=1m",
+	} {
+		c := CoerceNew(WithInitialDelay(time.Second))
+		if err := c.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("%q: expected an error", text)
+		}
+		if c.InitialDelay() != time.Second {
+			t.Errorf("%q: expected the backoff to be unchanged", text)
+		}
+	}
+}